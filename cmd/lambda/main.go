@@ -7,8 +7,17 @@ import (
 	"log/slog"
 
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector/kafka"
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector/ldap"
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector/mongodb"
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector/mysql"
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector/postgres"
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector/rabbitmq"
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector/redis"
 	"github.com/darthlynx/secret-rotation-lambda/internal/generator"
+	"github.com/darthlynx/secret-rotation-lambda/internal/iam"
 	"github.com/darthlynx/secret-rotation-lambda/internal/models"
 	"github.com/darthlynx/secret-rotation-lambda/internal/rotator"
 	"github.com/darthlynx/secret-rotation-lambda/internal/secretsmanager"
@@ -25,7 +34,19 @@ func init() {
 	}
 	smClient := secretsmanager.NewClient(cfg)
 	gen := generator.New()
-	rot = rotator.New(smClient, gen)
+	rot = rotator.New(smClient, gen,
+		rotator.WithConnector(postgres.Engine, postgres.New()),
+		rotator.WithConnector(mysql.EngineMySQL, mysql.New()),
+		rotator.WithConnector(mysql.EngineMariaDB, mysql.New()),
+		rotator.WithConnector(mongodb.EngineMongo, mongodb.New()),
+		rotator.WithConnector(mongodb.EngineMongoDB, mongodb.New()),
+		rotator.WithConnector(rabbitmq.Engine, rabbitmq.New()),
+		rotator.WithConnector(kafka.Engine, kafka.New()),
+		rotator.WithConnector(redis.Engine, redis.New()),
+		rotator.WithConnector(ldap.EngineLDAP, ldap.New()),
+		rotator.WithConnector(ldap.EngineActiveDirectory, ldap.New()),
+		rotator.WithIAMClient(iam.NewClient(cfg)),
+	)
 }
 
 // HandleRequest is the Lambda function handler
@@ -38,9 +59,24 @@ func HandleRequest(ctx context.Context, event json.RawMessage) (*models.Rotation
 		}, err
 	}
 
+	// An identity established by Lambda takes precedence over the one the caller claims
+	if id := invoker(ctx); id != "" {
+		req.RequestedBy = id
+	}
+
 	return rot.RotateSecret(ctx, req)
 }
 
+// invoker returns the Cognito identity the function was invoked with. Lambda does not pass
+// the IAM principal of other invocations to the function, so for those it returns "".
+func invoker(ctx context.Context) string {
+	lc, ok := lambdacontext.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return lc.Identity.CognitoIdentityID
+}
+
 func main() {
 	lambda.Start(HandleRequest)
 }
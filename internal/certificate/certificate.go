@@ -0,0 +1,221 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/generator"
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+)
+
+// Keys used in certificate and CA secrets.
+const (
+	FieldCertificate = "certificate"
+	FieldPrivateKey  = "private_key"
+	FieldChain       = "chain"
+)
+
+// clockSkew backdates NotBefore so freshly issued certificates are accepted by hosts with a slightly late clock.
+const clockSkew = 5 * time.Minute
+
+// CA is a certificate authority able to sign new certificates.
+type CA struct {
+	Certificate *x509.Certificate
+	Signer      crypto.Signer
+	ChainPEM    string // CA certificate followed by its own issuers
+}
+
+// Bundle is a newly issued certificate with its private key and chain.
+type Bundle struct {
+	CertificatePEM string
+	PrivateKeyPEM  string
+	ChainPEM       string
+	NotAfter       time.Time
+}
+
+// ParseCA parses the PEM encoded CA certificate, its PKCS#8 private key and optional issuer chain.
+func ParseCA(certPEM, keyPEM, chainPEM string) (*CA, error) {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+	if !cert.IsCA {
+		return nil, errors.New("CA certificate is not marked as a CA")
+	}
+
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, errors.New("CA private key is not PEM encoded")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("CA private key cannot sign")
+	}
+
+	chain := certPEM
+	if chainPEM != "" {
+		chain += chainPEM
+	}
+
+	return &CA{Certificate: cert, Signer: signer, ChainPEM: chain}, nil
+}
+
+// ParseCertificate decodes the first certificate of a PEM bundle.
+func ParseCertificate(certPEM string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// NeedsRenewal reports whether the certificate expires within renewBefore of now.
+func NeedsRenewal(certPEM string, renewBefore time.Duration, now time.Time) (bool, error) {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return false, err
+	}
+	return !now.Add(renewBefore).Before(cert.NotAfter), nil
+}
+
+// Issue generates a new key and CSR from the config and signs it with the CA. The certificate
+// never outlives the CA; when the CA cannot cover at least cfg.MinValidity (cfg.Validity when
+// unset) an error is returned instead of a certificate that is about to expire.
+func Issue(ca *CA, cfg models.CertificateConfig, now time.Time) (*Bundle, error) {
+	validity, err := time.ParseDuration(cfg.Validity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validity: %w", err)
+	}
+	minValidity := validity
+	if cfg.MinValidity != "" {
+		if minValidity, err = time.ParseDuration(cfg.MinValidity); err != nil {
+			return nil, fmt.Errorf("invalid min_validity: %w", err)
+		}
+	}
+	if remaining := ca.Certificate.NotAfter.Sub(now); remaining < minValidity {
+		if remaining <= 0 {
+			return nil, fmt.Errorf("CA certificate expired at %s", ca.Certificate.NotAfter.Format(time.RFC3339))
+		}
+		return nil, fmt.Errorf("CA certificate expires at %s, %s before the required validity of %s",
+			ca.Certificate.NotAfter.Format(time.RFC3339), (minValidity - remaining).Round(time.Second), minValidity)
+	}
+
+	kp, err := generator.GenerateKeyPair(cfg.KeyAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	csr, err := createCSR(kp.PrivateKey, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	notAfter := now.Add(validity)
+	if notAfter.After(ca.Certificate.NotAfter) {
+		notAfter = ca.Certificate.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:   serial,
+		Subject:        csr.Subject,
+		DNSNames:       csr.DNSNames,
+		IPAddresses:    csr.IPAddresses,
+		URIs:           csr.URIs,
+		NotBefore:      now.Add(-clockSkew),
+		NotAfter:       notAfter,
+		KeyUsage:       x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:    extKeyUsages(cfg.ExtKeyUsages),
+		AuthorityKeyId: ca.Certificate.SubjectKeyId,
+	}
+	if _, ok := kp.PrivateKey.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, csr.PublicKey, ca.Signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+
+	return &Bundle{
+		CertificatePEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		PrivateKeyPEM:  kp.PrivateKeyPEM,
+		ChainPEM:       ca.ChainPEM,
+		NotAfter:       notAfter,
+	}, nil
+}
+
+func createCSR(key crypto.Signer, cfg models.CertificateConfig) (*x509.CertificateRequest, error) {
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:         cfg.Subject.CommonName,
+			Organization:       cfg.Subject.Organization,
+			OrganizationalUnit: cfg.Subject.OrganizationalUnit,
+			Country:            cfg.Subject.Country,
+			Province:           cfg.Subject.Province,
+			Locality:           cfg.Subject.Locality,
+		},
+		DNSNames: cfg.DNSNames,
+	}
+	for _, ip := range cfg.IPAddresses {
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return nil, fmt.Errorf("invalid IP address: %s", ip)
+		}
+		template.IPAddresses = append(template.IPAddresses, parsed)
+	}
+	for _, u := range cfg.URIs {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URI %s: %w", u, err)
+		}
+		template.URIs = append(template.URIs, parsed)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSR: %w", err)
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSR: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid CSR signature: %w", err)
+	}
+	return csr, nil
+}
+
+func extKeyUsages(usages []string) []x509.ExtKeyUsage {
+	if len(usages) == 0 {
+		return []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	}
+	result := make([]x509.ExtKeyUsage, 0, len(usages))
+	for _, u := range usages {
+		switch u {
+		case "server_auth":
+			result = append(result, x509.ExtKeyUsageServerAuth)
+		case "client_auth":
+			result = append(result, x509.ExtKeyUsageClientAuth)
+		}
+	}
+	return result
+}
@@ -0,0 +1,207 @@
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+)
+
+func newTestCA(t *testing.T, notAfter time.Time) (certPEM, keyPEM string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal CA key: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestIssue(t *testing.T) {
+	now := time.Now()
+	caCert, caKey := newTestCA(t, now.Add(365*24*time.Hour))
+	ca, err := ParseCA(caCert, caKey, "")
+	if err != nil {
+		t.Fatalf("ParseCA() error: %v", err)
+	}
+
+	cfg := models.CertificateConfig{
+		KeyAlgorithm: models.KeyAlgorithmECDSAP256,
+		Subject:      models.CertificateSubject{CommonName: "billing.mesh.internal", Organization: []string{"Acme"}},
+		DNSNames:     []string{"billing.mesh.internal"},
+		IPAddresses:  []string{"10.0.0.12"},
+		URIs:         []string{"spiffe://mesh.internal/billing"},
+		Validity:     "720h",
+		ExtKeyUsages: []string{"client_auth"},
+	}
+
+	bundle, err := Issue(ca, cfg, now)
+	if err != nil {
+		t.Fatalf("Issue() error: %v", err)
+	}
+
+	cert, err := ParseCertificate(bundle.CertificatePEM)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM([]byte(bundle.ChainPEM))
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		DNSName:     "billing.mesh.internal",
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		CurrentTime: now,
+	}); err != nil {
+		t.Errorf("Issued certificate does not verify against CA: %v", err)
+	}
+
+	if cert.Subject.CommonName != "billing.mesh.internal" {
+		t.Errorf("CommonName = %s, want billing.mesh.internal", cert.Subject.CommonName)
+	}
+	if len(cert.IPAddresses) != 1 || !cert.IPAddresses[0].Equal([]byte{10, 0, 0, 12}) {
+		t.Errorf("IPAddresses = %v, want [10.0.0.12]", cert.IPAddresses)
+	}
+	if len(cert.URIs) != 1 || cert.URIs[0].String() != "spiffe://mesh.internal/billing" {
+		t.Errorf("URIs = %v, want [spiffe://mesh.internal/billing]", cert.URIs)
+	}
+	if !cert.NotAfter.Equal(bundle.NotAfter.Truncate(time.Second)) {
+		t.Errorf("NotAfter = %v, want %v", cert.NotAfter, bundle.NotAfter)
+	}
+
+	block, _ := pem.Decode([]byte(bundle.PrivateKeyPEM))
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse issued private key: %v", err)
+	}
+	if !key.(*ecdsa.PrivateKey).PublicKey.Equal(cert.PublicKey) {
+		t.Errorf("Issued private key does not match certificate")
+	}
+}
+
+func TestIssue_CappedByCANotAfter(t *testing.T) {
+	now := time.Now()
+	caNotAfter := now.Add(48 * time.Hour)
+	caCert, caKey := newTestCA(t, caNotAfter)
+	ca, err := ParseCA(caCert, caKey, "")
+	if err != nil {
+		t.Fatalf("ParseCA() error: %v", err)
+	}
+
+	bundle, err := Issue(ca, models.CertificateConfig{
+		KeyAlgorithm: models.KeyAlgorithmEd25519,
+		Subject:      models.CertificateSubject{CommonName: "short-lived"},
+		Validity:     "720h",
+		MinValidity:  "24h",
+	}, now)
+	if err != nil {
+		t.Fatalf("Issue() error: %v", err)
+	}
+
+	if !bundle.NotAfter.Equal(ca.Certificate.NotAfter) {
+		t.Errorf("NotAfter = %v, want CA NotAfter %v", bundle.NotAfter, ca.Certificate.NotAfter)
+	}
+}
+
+func TestIssue_CATooShort(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		caNotAfter  time.Time
+		minValidity string
+	}{
+		{name: "expired CA", caNotAfter: now.Add(-time.Hour), minValidity: "1h"},
+		{name: "requested validity", caNotAfter: now.Add(48 * time.Hour)},
+		{name: "minimum validity", caNotAfter: now.Add(48 * time.Hour), minValidity: "72h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caCert, caKey := newTestCA(t, tt.caNotAfter)
+			ca, err := ParseCA(caCert, caKey, "")
+			if err != nil {
+				t.Fatalf("ParseCA() error: %v", err)
+			}
+
+			_, err = Issue(ca, models.CertificateConfig{
+				KeyAlgorithm: models.KeyAlgorithmEd25519,
+				Subject:      models.CertificateSubject{CommonName: "short-lived"},
+				Validity:     "720h",
+				MinValidity:  tt.minValidity,
+			}, now)
+			if err == nil {
+				t.Error("Issue() succeeded with a CA that cannot cover the validity")
+			}
+		})
+	}
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	now := time.Now()
+	caCert, _ := newTestCA(t, now.Add(10*24*time.Hour))
+
+	tests := []struct {
+		name        string
+		renewBefore time.Duration
+		want        bool
+	}{
+		{name: "outside renewal window", renewBefore: 5 * 24 * time.Hour, want: false},
+		{name: "inside renewal window", renewBefore: 30 * 24 * time.Hour, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NeedsRenewal(caCert, tt.renewBefore, now)
+			if err != nil {
+				t.Fatalf("NeedsRenewal() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NeedsRenewal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCA_NotCA(t *testing.T) {
+	now := time.Now()
+	caCert, caKey := newTestCA(t, now.Add(24*time.Hour))
+	ca, _ := ParseCA(caCert, caKey, "")
+	bundle, err := Issue(ca, models.CertificateConfig{
+		KeyAlgorithm: models.KeyAlgorithmEd25519,
+		Subject:      models.CertificateSubject{CommonName: "leaf"},
+		Validity:     "1h",
+	}, now)
+	if err != nil {
+		t.Fatalf("Issue() error: %v", err)
+	}
+
+	if _, err := ParseCA(bundle.CertificatePEM, bundle.PrivateKeyPEM, ""); err == nil {
+		t.Errorf("ParseCA() expected error for leaf certificate, got nil")
+	}
+}
@@ -0,0 +1,177 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Secret fields read by connectors. The names follow the AWS rotation templates,
+// so secrets managed by those templates can be rotated unchanged.
+const (
+	FieldEngine    = "engine"
+	FieldHost      = "host"
+	FieldPort      = "port"
+	FieldUsername  = "username"
+	FieldPassword  = "password"
+	FieldDBName    = "dbname"
+	FieldTLS       = "tls"       // connect over TLS, for connectors without their own TLS settings
+	FieldURI       = "uri"       // connection string, for connectors that accept one instead of host and port
+	FieldMasterARN = "masterarn" // secret holding admin credentials used to change the password
+
+	// FieldManagementURL is the address of an HTTP management API, for connectors that change
+	// passwords through one rather than the connection applications use.
+	FieldManagementURL = "management_url"
+
+	// FieldPreviousUsername records the user replaced by the last ephemeral-users rotation,
+	// which is dropped by the next one.
+	FieldPreviousUsername = "previous_username"
+)
+
+// Credentials are the connection details stored in a key-value secret.
+type Credentials struct {
+	Engine   string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	DBName   string
+	TLS      bool
+	URI      string
+
+	ManagementURL string
+}
+
+// uriOnlyEngines may name their servers in the uri field alone, as a MongoDB replica set
+// connection string does. The names match the mongodb connector's engine values.
+var uriOnlyEngines = map[string]bool{"mongo": true, "mongodb": true}
+
+// Connector applies a rotated secret to the system that uses it, following the
+// setSecret and testSecret steps of Secrets Manager rotation.
+type Connector interface {
+	// SetSecret logs in as admin and changes the password of pending.Username to pending.Password.
+	SetSecret(ctx context.Context, admin, pending Credentials) error
+	// TestSecret checks that the pending credentials can log in.
+	TestSecret(ctx context.Context, pending Credentials) error
+}
+
+// Finisher is implemented by connectors that clean up after the new version is promoted.
+type Finisher interface {
+	// FinishSecret runs once pending has become the current version. previous holds the
+	// credentials that were current before the rotation.
+	FinishSecret(ctx context.Context, admin, pending, previous Credentials) error
+}
+
+// Reverter is implemented by connectors whose SetSecret does not replace the password, so that
+// setting the previous password again would not undo it.
+type Reverter interface {
+	// RevertSecret undoes SetSecret after the pending credentials failed their test or could
+	// not be promoted, leaving previous as the only working credentials.
+	RevertSecret(ctx context.Context, admin, pending, previous Credentials) error
+}
+
+// Redacted replaces credentials in connector output.
+const Redacted = "[REDACTED]"
+
+// Redact removes every non-empty secret from msg. Database drivers may echo statements or
+// connection strings in their errors, so connector errors are redacted before they are returned.
+func Redact(msg string, secrets ...string) string {
+	for _, s := range secrets {
+		if s != "" {
+			msg = strings.ReplaceAll(msg, s, Redacted)
+		}
+	}
+	return msg
+}
+
+// UserCloner is implemented by connectors that can create users for the alternating-users strategy.
+type UserCloner interface {
+	// CloneUser creates user with the privileges of template, unless it already exists.
+	// An existing user is left unchanged, its password is set by SetSecret.
+	CloneUser(ctx context.Context, admin Credentials, template string, user Credentials) error
+}
+
+// UserManager is implemented by connectors that support the ephemeral-users strategy.
+type UserManager interface {
+	// CreateUser creates a login user holding the template role.
+	CreateUser(ctx context.Context, admin Credentials, templateRole string, user Credentials) error
+	// DropUser removes a user. A user that does not exist is not an error.
+	DropUser(ctx context.Context, admin Credentials, username string) error
+}
+
+// ParseCredentials reads the connection fields from a key-value secret. The port may be
+// stored as a number or a string. The host may be left out when a management URL is given,
+// and in MongoDB secrets when a connection string is given. A connection string must not
+// carry a password: only the password field is rotated, so it would keep the old one.
+func ParseCredentials(secret map[string]interface{}) (Credentials, error) {
+	creds := Credentials{
+		Engine:   stringField(secret, FieldEngine),
+		Host:     stringField(secret, FieldHost),
+		Username: stringField(secret, FieldUsername),
+		Password: stringField(secret, FieldPassword),
+		DBName:   stringField(secret, FieldDBName),
+		URI:      stringField(secret, FieldURI),
+
+		ManagementURL: stringField(secret, FieldManagementURL),
+	}
+
+	switch port := secret[FieldPort].(type) {
+	case nil:
+	case float64:
+		creds.Port = int(port)
+	case string:
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("invalid %s: %q", FieldPort, port)
+		}
+		creds.Port = p
+	default:
+		return Credentials{}, fmt.Errorf("invalid %s type: %T", FieldPort, port)
+	}
+
+	switch tls := secret[FieldTLS].(type) {
+	case nil:
+	case bool:
+		creds.TLS = tls
+	default:
+		return Credentials{}, fmt.Errorf("invalid %s type: %T", FieldTLS, tls)
+	}
+
+	if creds.Host == "" && creds.ManagementURL == "" && (creds.URI == "" || !uriOnlyEngines[creds.Engine]) {
+		return Credentials{}, errors.New("secret has no " + FieldHost)
+	}
+	if uriHasPassword(creds.URI) {
+		return Credentials{}, fmt.Errorf("%s must not contain a password, it is taken from %s", FieldURI, FieldPassword)
+	}
+	if creds.Username == "" {
+		return Credentials{}, errors.New("secret has no " + FieldUsername)
+	}
+	return creds, nil
+}
+
+// Engine returns the engine named in a key-value secret, or "" when there is none.
+func Engine(secret map[string]interface{}) string {
+	return stringField(secret, FieldEngine)
+}
+
+// uriHasPassword reports whether the user info of a connection string has a password. The
+// authority is split by hand, since url.Parse rejects the host lists of MongoDB URIs.
+func uriHasPassword(uri string) bool {
+	i := strings.Index(uri, "://")
+	if i < 0 {
+		return false
+	}
+	authority := uri[i+len("://"):]
+	if end := strings.IndexAny(authority, "/?#"); end >= 0 {
+		authority = authority[:end]
+	}
+	at := strings.LastIndexByte(authority, '@')
+	return at >= 0 && strings.Contains(authority[:at], ":")
+}
+
+func stringField(secret map[string]interface{}, key string) string {
+	s, _ := secret[key].(string)
+	return s
+}
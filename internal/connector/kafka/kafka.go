@@ -0,0 +1,176 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// Engine is the secret engine value handled by this connector.
+const Engine = "kafka"
+
+const (
+	defaultPort    = 9092
+	defaultTimeout = 30 * time.Second
+
+	// iterations is the Kafka minimum for SCRAM-SHA-512, matching kafka-configs.sh.
+	iterations = 4096
+
+	// A new credential reaches the other brokers asynchronously, so TestSecret retries a
+	// failed login with backoff until propagationTimeout.
+	propagationTimeout = 30 * time.Second
+	minRetryInterval   = 500 * time.Millisecond
+	maxRetryInterval   = 5 * time.Second
+)
+
+// errCleartext is returned before a salted password would be sent over a plain connection.
+var errCleartext = errors.New("refusing to send a SCRAM credential over an unencrypted connection, set tls in the secret")
+
+// Connector rotates SASL/SCRAM-SHA-512 credentials of a Kafka principal with the
+// AlterUserSCRAMCredentials API (Kafka 2.7+). The host field holds the bootstrap brokers,
+// either one host or a comma separated list of host:port pairs.
+//
+// The salted password sent to the broker is all a client needs to log in, so SetSecret
+// requires tls. TestSecret only runs a SCRAM exchange, which does not reveal the password.
+type Connector struct {
+	rootCAs            *x509.CertPool // nil uses the system roots
+	propagationTimeout time.Duration
+	retryInterval      time.Duration // first backoff, doubled up to maxRetryInterval
+}
+
+// New creates a Kafka connector.
+func New() *Connector {
+	return &Connector{propagationTimeout: propagationTimeout, retryInterval: minRetryInterval}
+}
+
+// SetSecret logs in as admin and replaces the SCRAM-SHA-512 credential of the pending user.
+// The request goes to the controller, which ZooKeeper based clusters require.
+func (c *Connector) SetSecret(ctx context.Context, admin, pending connector.Credentials) error {
+	if !admin.TLS {
+		return errCleartext
+	}
+	client, err := c.newClient(admin)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+	results, err := kadm.NewClient(client).AlterUserSCRAMs(ctx, nil, []kadm.UpsertSCRAM{{
+		User:       pending.Username,
+		Mechanism:  kadm.ScramSha512,
+		Iterations: iterations,
+		Password:   pending.Password,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to alter SCRAM credentials of %s: %w", pending.Username, err)
+	}
+	for _, result := range results.Sorted() {
+		if result.Err != nil {
+			return fmt.Errorf("failed to alter SCRAM credentials of %s: %w%s", result.User, result.Err, errorMessage(result.ErrMessage))
+		}
+	}
+	return nil
+}
+
+// TestSecret completes a SASL/SCRAM handshake with the pending credentials. A rejected login
+// is retried until the new credential had time to reach the broker.
+func (c *Connector) TestSecret(ctx context.Context, pending connector.Credentials) error {
+	deadline := time.Now().Add(c.propagationTimeout)
+	wait := c.retryInterval
+	for {
+		err := c.ping(ctx, pending)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, kerr.SaslAuthenticationFailed) || time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("failed to authenticate as %s: %w", pending.Username, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to authenticate as %s: %w", pending.Username, errors.Join(err, ctx.Err()))
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, maxRetryInterval)
+	}
+}
+
+// ping logs in to the first reachable broker. Brokers are only tried in turn when they
+// cannot be reached, a failed login is returned at once.
+func (c *Connector) ping(ctx context.Context, creds connector.Credentials) error {
+	client, err := c.newClient(creds)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+	return client.Ping(ctx)
+}
+
+// newClient creates a client authenticating with SCRAM-SHA-512. Kafka does not SASLprep
+// passwords, and neither does the client.
+func (c *Connector) newClient(creds connector.Credentials) (*kgo.Client, error) {
+	addrs := bootstrapAddrs(creds)
+	if len(addrs) == 0 {
+		return nil, errors.New("secret has no brokers")
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(addrs...),
+		kgo.SASL(scram.Auth{User: creds.Username, Pass: creds.Password}.AsSha512Mechanism()),
+	}
+	if creds.TLS {
+		// The server name is set per broker from the address dialed
+		opts = append(opts, kgo.DialTLSConfig(&tls.Config{RootCAs: c.rootCAs, MinVersion: tls.VersionTLS12}))
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka client: %w", err)
+	}
+	return client, nil
+}
+
+// bootstrapAddrs returns the broker addresses of a secret, adding the port field or the
+// default port to hosts without one.
+func bootstrapAddrs(creds connector.Credentials) []string {
+	port := defaultPort
+	if creds.Port != 0 {
+		port = creds.Port
+	}
+
+	var addrs []string
+	for _, host := range strings.Split(creds.Host, ",") {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		addrs = append(addrs, host)
+	}
+	return addrs
+}
+
+func errorMessage(msg string) string {
+	if msg == "" {
+		return ""
+	}
+	return ": " + msg
+}
@@ -0,0 +1,424 @@
+package kafka
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"github.com/xdg-go/scram"
+)
+
+const (
+	mechanism      = "SCRAM-SHA-512"
+	keyApiVersions = 18
+	scramSHA512    = int8(kadm.ScramSha512)
+)
+
+// fakeCluster runs in-process TLS brokers that share SCRAM-SHA-512 users. Metadata names the
+// last broker as the controller, and only the controller accepts credential changes.
+type fakeCluster struct {
+	mu      sync.Mutex
+	users   map[string]scram.StoredCredentials
+	brokers []net.Listener
+	roots   *x509.CertPool
+	altered []int // brokers that handled AlterUserSCRAMCredentials
+
+	// lag is the number of logins that still see the old credential after a change
+	lag     int
+	pending map[string]scram.StoredCredentials
+}
+
+func newFakeCluster(t *testing.T, brokers int, passwords map[string]string) *fakeCluster {
+	t.Helper()
+	c := &fakeCluster{users: map[string]scram.StoredCredentials{}, pending: map[string]scram.StoredCredentials{}}
+	cert, roots := selfSignedCert(t)
+	c.roots = roots
+	for user, password := range passwords {
+		salt := []byte("salt-" + user)
+		salted, err := pbkdf2.Key(sha512.New, password, salt, iterations, sha512.Size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.users[user] = storedCredentials(salt, salted, iterations)
+	}
+	for i := range brokers {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		ln = tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{cert}})
+		t.Cleanup(func() { ln.Close() })
+		c.brokers = append(c.brokers, ln)
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				go c.serve(i, conn)
+			}
+		}()
+	}
+	return c
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 and a pool trusting it.
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kafka.test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, roots
+}
+
+// storedCredentials derives what a broker stores from an upserted salted password.
+func storedCredentials(salt, salted []byte, iters int) scram.StoredCredentials {
+	mac := func(key []byte, data string) []byte {
+		h := hmac.New(sha512.New, key)
+		h.Write([]byte(data))
+		return h.Sum(nil)
+	}
+	clientKey := sha512.Sum512(mac(salted, "Client Key"))
+	return scram.StoredCredentials{
+		KeyFactors: scram.KeyFactors{Salt: string(salt), Iters: iters},
+		StoredKey:  clientKey[:],
+		ServerKey:  mac(salted, "Server Key"),
+	}
+}
+
+func (c *fakeCluster) addr(i int) string {
+	return c.brokers[i].Addr().String()
+}
+
+func (c *fakeCluster) creds(username, password string, brokers ...int) connector.Credentials {
+	var hosts []string
+	for _, i := range brokers {
+		hosts = append(hosts, c.addr(i))
+	}
+	return connector.Credentials{Host: strings.Join(hosts, ","), Username: username, Password: password, TLS: true}
+}
+
+func (c *fakeCluster) lookup(user string) (scram.StoredCredentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if next, ok := c.pending[user]; ok {
+		if c.lag > 0 {
+			c.lag--
+		} else {
+			c.users[user] = next
+			delete(c.pending, user)
+		}
+	}
+	creds, ok := c.users[user]
+	if !ok {
+		return scram.StoredCredentials{}, errors.New("unknown user")
+	}
+	return creds, nil
+}
+
+func (c *fakeCluster) serve(id int, conn net.Conn) {
+	defer conn.Close()
+	server, _ := scram.SHA512.NewServer(c.lookup)
+	var conv *scram.ServerConversation
+	authenticated := false
+
+	for {
+		req, correlationID, err := readRequest(conn)
+		if err != nil {
+			return
+		}
+
+		var resp kmsg.Response
+		switch req := req.(type) {
+		case *kmsg.ApiVersionsRequest:
+			r := req.ResponseKind().(*kmsg.ApiVersionsResponse)
+			for _, key := range []int16{3, 17, 18, 36, 51} {
+				k := kmsg.NewApiVersionsResponseApiKey()
+				k.ApiKey, k.MaxVersion = key, kmsg.RequestForKey(key).MaxVersion()
+				r.ApiKeys = append(r.ApiKeys, k)
+			}
+			resp = r
+		case *kmsg.SASLHandshakeRequest:
+			r := req.ResponseKind().(*kmsg.SASLHandshakeResponse)
+			r.SupportedMechanisms = []string{mechanism}
+			if req.Mechanism != mechanism {
+				r.ErrorCode = kerr.UnsupportedSaslMechanism.Code
+			}
+			conv = server.NewConversation()
+			resp = r
+		case *kmsg.SASLAuthenticateRequest:
+			r := req.ResponseKind().(*kmsg.SASLAuthenticateResponse)
+			msg, err := conv.Step(string(req.SASLAuthBytes))
+			if err != nil {
+				r.ErrorCode = kerr.SaslAuthenticationFailed.Code
+				r.ErrorMessage = kmsg.StringPtr("Authentication failed during authentication due to invalid credentials with SASL mechanism " + mechanism)
+			}
+			r.SASLAuthBytes = []byte(msg)
+			authenticated = conv.Valid()
+			resp = r
+		case *kmsg.MetadataRequest:
+			if !authenticated {
+				return
+			}
+			r := req.ResponseKind().(*kmsg.MetadataResponse)
+			for i := range c.brokers {
+				host, port, _ := net.SplitHostPort(c.addr(i))
+				p, _ := strconv.Atoi(port)
+				b := kmsg.NewMetadataResponseBroker()
+				b.NodeID, b.Host, b.Port = int32(i), host, int32(p)
+				r.Brokers = append(r.Brokers, b)
+			}
+			r.ControllerID = int32(len(c.brokers) - 1)
+			resp = r
+		case *kmsg.AlterUserSCRAMCredentialsRequest:
+			if !authenticated {
+				return
+			}
+			resp = c.alter(id, req)
+		default:
+			return
+		}
+
+		if err := writeResponse(conn, req.Key(), resp, correlationID); err != nil {
+			return
+		}
+	}
+}
+
+func (c *fakeCluster) alter(id int, req *kmsg.AlterUserSCRAMCredentialsRequest) kmsg.Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := req.ResponseKind().(*kmsg.AlterUserSCRAMCredentialsResponse)
+	for _, u := range req.Upsertions {
+		result := kmsg.NewAlterUserSCRAMCredentialsResponseResult()
+		result.User = u.Name
+		switch {
+		case id != len(c.brokers)-1:
+			result.ErrorCode = kerr.NotController.Code
+		case u.Mechanism != scramSHA512 || u.Iterations < iterations:
+			result.ErrorCode = kerr.UnacceptableCredential.Code
+		default:
+			c.pending[u.Name] = storedCredentials(u.Salt, u.SaltedPassword, int(u.Iterations))
+			c.altered = append(c.altered, id)
+		}
+		r.Results = append(r.Results, result)
+	}
+	return r
+}
+
+func readRequest(conn net.Conn) (kmsg.Request, int32, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, 0, err
+	}
+	b := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(conn, b); err != nil {
+		return nil, 0, err
+	}
+
+	key := int16(binary.BigEndian.Uint16(b))
+	version := int16(binary.BigEndian.Uint16(b[2:]))
+	correlationID := int32(binary.BigEndian.Uint32(b[4:]))
+	clientIDLen := int16(binary.BigEndian.Uint16(b[8:]))
+	b = b[10:]
+	if clientIDLen > 0 {
+		b = b[clientIDLen:]
+	}
+
+	req := kmsg.RequestForKey(key)
+	if req == nil {
+		return nil, 0, errors.New("unknown request")
+	}
+	req.SetVersion(version)
+	if req.IsFlexible() {
+		b = b[1:] // no tagged fields
+	}
+	return req, correlationID, req.ReadFrom(b)
+}
+
+func writeResponse(conn net.Conn, key int16, resp kmsg.Response, correlationID int32) error {
+	b := binary.BigEndian.AppendUint32(make([]byte, 4), uint32(correlationID))
+	if resp.IsFlexible() && key != keyApiVersions {
+		b = append(b, 0)
+	}
+	b = resp.AppendTo(b)
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	_, err := conn.Write(b)
+	return err
+}
+
+// newTestConnector returns a connector that trusts the cluster and gives up on a rejected
+// login quickly.
+func newTestConnector(cluster *fakeCluster) *Connector {
+	c := &Connector{propagationTimeout: 200 * time.Millisecond, retryInterval: 10 * time.Millisecond}
+	if cluster != nil {
+		c.rootCAs = cluster.roots
+	}
+	return c
+}
+
+func TestRotation(t *testing.T) {
+	cluster := newFakeCluster(t, 2, map[string]string{"admin": "admin-pass", "app": "old-pass"})
+	c := newTestConnector(cluster)
+	ctx := context.Background()
+	admin := cluster.creds("admin", "admin-pass", 0, 1)
+	pending := cluster.creds("app", "new-pass", 0, 1)
+
+	if err := c.TestSecret(ctx, pending); err == nil {
+		t.Fatal("TestSecret() succeeded before the password was changed")
+	}
+	if err := c.SetSecret(ctx, admin, pending); err != nil {
+		t.Fatalf("SetSecret() error: %v", err)
+	}
+	if len(cluster.altered) != 1 || cluster.altered[0] != 1 {
+		t.Errorf("credentials altered on brokers %v, want the controller 1", cluster.altered)
+	}
+	if err := c.TestSecret(ctx, pending); err != nil {
+		t.Errorf("TestSecret() error: %v", err)
+	}
+	if err := c.TestSecret(ctx, cluster.creds("app", "old-pass", 0)); err == nil {
+		t.Error("old password still works")
+	}
+}
+
+func TestTestSecret_WrongPassword(t *testing.T) {
+	cluster := newFakeCluster(t, 1, map[string]string{"app": "old-pass"})
+
+	err := newTestConnector(cluster).TestSecret(context.Background(), cluster.creds("app", "wrong", 0))
+	if err == nil || !strings.Contains(err.Error(), "invalid credentials") {
+		t.Errorf("TestSecret() error = %v, want authentication failure", err)
+	}
+}
+
+func TestTestSecret_WaitsForPropagation(t *testing.T) {
+	cluster := newFakeCluster(t, 1, map[string]string{"admin": "admin-pass", "app": "old-pass"})
+	c := newTestConnector(cluster)
+	ctx := context.Background()
+	pending := cluster.creds("app", "new-pass", 0)
+
+	if err := c.SetSecret(ctx, cluster.creds("admin", "admin-pass", 0), pending); err != nil {
+		t.Fatalf("SetSecret() error: %v", err)
+	}
+	cluster.mu.Lock()
+	cluster.lag = 3
+	cluster.mu.Unlock()
+
+	if err := c.TestSecret(ctx, pending); err != nil {
+		t.Errorf("TestSecret() error: %v", err)
+	}
+}
+
+func TestSetSecret_RequiresTLS(t *testing.T) {
+	cluster := newFakeCluster(t, 1, map[string]string{"admin": "admin-pass", "app": "old-pass"})
+	admin := cluster.creds("admin", "admin-pass", 0)
+	admin.TLS = false
+
+	err := newTestConnector(cluster).SetSecret(context.Background(), admin, cluster.creds("app", "new-pass", 0))
+	if !errors.Is(err, errCleartext) {
+		t.Errorf("SetSecret() error = %v, want %v", err, errCleartext)
+	}
+	if len(cluster.altered) != 0 {
+		t.Errorf("credentials altered without TLS")
+	}
+}
+
+func TestTestSecret_PlaintextToTLSListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// A TLS listener answers a plaintext request with an alert record
+		conn.Write([]byte{0x15, 0x03, 0x03, 0x00, 0x02, 0x02, 0x46})
+	}()
+
+	err = newTestConnector(nil).TestSecret(context.Background(), connector.Credentials{Host: ln.Addr().String(), Username: "app"})
+	if err == nil || !strings.Contains(err.Error(), "tls") {
+		t.Errorf("TestSecret() error = %v, want a hint about tls", err)
+	}
+}
+
+func TestConnect_SkipsUnreachableBroker(t *testing.T) {
+	cluster := newFakeCluster(t, 1, map[string]string{"app": "pass"})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := ln.Addr().String()
+	ln.Close()
+
+	creds := connector.Credentials{Host: down + "," + cluster.addr(0), Username: "app", Password: "pass", TLS: true}
+	if err := newTestConnector(cluster).TestSecret(context.Background(), creds); err != nil {
+		t.Errorf("TestSecret() error: %v", err)
+	}
+}
+
+func TestBootstrapAddrs(t *testing.T) {
+	tests := []struct {
+		name  string
+		creds connector.Credentials
+		want  string
+	}{
+		{name: "default port", creds: connector.Credentials{Host: "kafka.internal"}, want: "kafka.internal:9092"},
+		{name: "port field", creds: connector.Credentials{Host: "kafka.internal", Port: 9096}, want: "kafka.internal:9096"},
+		{
+			name:  "broker list",
+			creds: connector.Credentials{Host: "b-1.msk:9096, b-2.msk:9096,b-3.msk", Port: 9096},
+			want:  "b-1.msk:9096,b-2.msk:9096,b-3.msk:9096",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(bootstrapAddrs(tt.creds), ","); got != tt.want {
+				t.Errorf("bootstrapAddrs() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
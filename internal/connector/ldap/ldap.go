@@ -0,0 +1,217 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Engine values handled by this connector.
+const (
+	EngineLDAP            = "ldap"
+	EngineActiveDirectory = "activedirectory"
+)
+
+const (
+	defaultPort    = 389
+	defaultTLSPort = 636
+	defaultTimeout = 30 * time.Second
+)
+
+// session is the part of an LDAP connection the connector needs. *ldap.Conn implements it.
+type session interface {
+	StartTLS(config *tls.Config) error
+	TLSConnectionState() (tls.ConnectionState, bool)
+	Bind(username, password string) error
+	PasswordModify(req *ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error)
+	Modify(req *ldap.ModifyRequest) error
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// Connector rotates directory passwords. Plain LDAP servers get the password modify
+// extended operation (RFC 3062); Active Directory gets a unicodePwd modification.
+//
+// Binds and password changes send passwords in the clear, so they are only made over TLS:
+// ldaps:// connections are encrypted from the start and ldap:// connections are upgraded
+// with StartTLS. A server that refuses StartTLS is never sent a password.
+//
+// The username is the bind DN. For Active Directory it may also be a userPrincipalName or
+// sAMAccountName, which is resolved to a DN under the domain's default naming context.
+type Connector struct {
+	dial func(ctx context.Context, creds connector.Credentials) (session, error)
+}
+
+// New creates an LDAP/Active Directory connector.
+func New() *Connector {
+	return &Connector{dial: dial}
+}
+
+// SetSecret binds as admin and changes the password of the pending user. When admin is the
+// pending account itself, admin holds the current password and the change is made as a
+// self-service change, so directory password policy applies as it would for the user.
+func (c *Connector) SetSecret(ctx context.Context, admin, pending connector.Credentials) error {
+	ad := pending.Engine == EngineActiveDirectory
+	s, err := c.bind(ctx, admin)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	self := admin.Username == pending.Username
+	if !ad {
+		req := ldap.NewPasswordModifyRequest(pending.Username, "", pending.Password)
+		if self {
+			// An empty identity names the bound user; the old password lets servers
+			// enforce the same checks as an interactive change
+			req = ldap.NewPasswordModifyRequest("", admin.Password, pending.Password)
+		}
+		if _, err := s.PasswordModify(req); err != nil {
+			return fmt.Errorf("failed to change password of %s: %w", pending.Username, err)
+		}
+		return nil
+	}
+
+	dn, err := resolveDN(s, pending.Username)
+	if err != nil {
+		return err
+	}
+	req := ldap.NewModifyRequest(dn, nil)
+	if self {
+		// Active Directory treats delete+add of unicodePwd as a user password change and a
+		// replace as an administrative reset
+		req.Delete("unicodePwd", []string{encodePassword(admin.Password)})
+		req.Add("unicodePwd", []string{encodePassword(pending.Password)})
+	} else {
+		req.Replace("unicodePwd", []string{encodePassword(pending.Password)})
+	}
+	if err := s.Modify(req); err != nil {
+		return fmt.Errorf("failed to change password of %s: %w", pending.Username, err)
+	}
+	return nil
+}
+
+// TestSecret binds with the pending credentials.
+func (c *Connector) TestSecret(ctx context.Context, pending connector.Credentials) error {
+	s, err := c.bind(ctx, pending)
+	if err != nil {
+		return err
+	}
+	return s.Close()
+}
+
+func (c *Connector) bind(ctx context.Context, creds connector.Credentials) (session, error) {
+	s, err := c.dial(ctx, creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect as %s: %w", creds.Username, err)
+	}
+	if _, ok := s.TLSConnectionState(); !ok {
+		if err := s.StartTLS(tlsConfig(creds)); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to start TLS, passwords are only sent over LDAPS or StartTLS: %w", err)
+		}
+	}
+	if err := s.Bind(creds.Username, creds.Password); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to bind as %s: %w", creds.Username, err)
+	}
+	return s, nil
+}
+
+// resolveDN returns username when it is a DN, and otherwise looks up the Active Directory
+// account whose userPrincipalName or sAMAccountName matches it.
+func resolveDN(s session, username string) (string, error) {
+	if dn, err := ldap.ParseDN(username); err == nil && len(dn.RDNs) > 0 {
+		return username, nil
+	}
+
+	rootDSE, err := s.Search(ldap.NewSearchRequest("", ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		0, 0, false, "(objectClass=*)", []string{"defaultNamingContext"}, nil))
+	if err != nil {
+		return "", fmt.Errorf("failed to read RootDSE: %w", err)
+	}
+	if len(rootDSE.Entries) == 0 || rootDSE.Entries[0].GetAttributeValue("defaultNamingContext") == "" {
+		return "", errors.New("RootDSE has no defaultNamingContext")
+	}
+	base := rootDSE.Entries[0].GetAttributeValue("defaultNamingContext")
+
+	// DOMAIN\user logons carry the sAMAccountName after the backslash
+	account := username
+	if i := strings.LastIndex(username, `\`); i >= 0 {
+		account = username[i+1:]
+	}
+	filter := fmt.Sprintf("(&(objectClass=user)(|(userPrincipalName=%s)(sAMAccountName=%s)))",
+		ldap.EscapeFilter(username), ldap.EscapeFilter(account))
+	res, err := s.Search(ldap.NewSearchRequest(base, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, 0, false, filter, []string{"distinguishedName"}, nil))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return "", fmt.Errorf("failed to look up %s: %w", username, err)
+	}
+	switch {
+	case res == nil || len(res.Entries) == 0:
+		return "", fmt.Errorf("user %s not found under %s", username, base)
+	case len(res.Entries) > 1:
+		return "", fmt.Errorf("user %s matches more than one account under %s", username, base)
+	}
+	return res.Entries[0].DN, nil
+}
+
+// encodePassword returns the unicodePwd value for password: the quoted password in UTF-16LE.
+func encodePassword(password string) string {
+	units := utf16.Encode([]rune(`"` + password + `"`))
+	b := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return string(b)
+}
+
+// serverURL returns the uri field, or an ldap:// or ldaps:// URL built from host and port.
+func serverURL(creds connector.Credentials) string {
+	if creds.URI != "" {
+		return creds.URI
+	}
+	scheme, port := "ldap", defaultPort
+	if creds.TLS {
+		scheme, port = "ldaps", defaultTLSPort
+	}
+	if creds.Port != 0 {
+		port = creds.Port
+	}
+	return scheme + "://" + net.JoinHostPort(creds.Host, strconv.Itoa(port))
+}
+
+// tlsConfig verifies the server certificate against the host name the connector dials.
+func tlsConfig(creds connector.Credentials) *tls.Config {
+	host := creds.Host
+	if u, err := url.Parse(serverURL(creds)); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+}
+
+func dial(ctx context.Context, creds connector.Credentials) (session, error) {
+	timeout := defaultTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	conn, err := ldap.DialURL(serverURL(creds),
+		ldap.DialWithDialer(&net.Dialer{Timeout: timeout}), ldap.DialWithTLSConfig(tlsConfig(creds)))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+	return conn, nil
+}
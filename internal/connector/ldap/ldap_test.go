@@ -0,0 +1,252 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const appDN = "CN=app,OU=Service Accounts,DC=corp,DC=example"
+
+// fakeDirectory keeps passwords by bind name and records the changes made through it.
+type fakeDirectory struct {
+	passwords  map[string]string
+	ldaps      bool // connections are encrypted from the start
+	noStartTLS bool
+	startTLS   int
+	binds      int
+	modifies   []*ldap.ModifyRequest
+	pwModify   []*ldap.PasswordModifyRequest
+	filters    []string
+}
+
+func (d *fakeDirectory) dial(_ context.Context, _ connector.Credentials) (session, error) {
+	return &fakeSession{dir: d, tls: d.ldaps}, nil
+}
+
+type fakeSession struct {
+	dir *fakeDirectory
+	tls bool
+}
+
+func (s *fakeSession) StartTLS(*tls.Config) error {
+	if s.dir.noStartTLS {
+		return ldap.NewError(ldap.LDAPResultProtocolError, errors.New("unsupported extended operation"))
+	}
+	s.dir.startTLS++
+	s.tls = true
+	return nil
+}
+
+func (s *fakeSession) TLSConnectionState() (tls.ConnectionState, bool) {
+	return tls.ConnectionState{}, s.tls
+}
+
+func (s *fakeSession) Bind(username, password string) error {
+	s.dir.binds++
+	if p, ok := s.dir.passwords[username]; !ok || p != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+	return nil
+}
+
+func (s *fakeSession) PasswordModify(req *ldap.PasswordModifyRequest) (*ldap.PasswordModifyResult, error) {
+	s.dir.pwModify = append(s.dir.pwModify, req)
+	return &ldap.PasswordModifyResult{}, nil
+}
+
+func (s *fakeSession) Modify(req *ldap.ModifyRequest) error {
+	s.dir.modifies = append(s.dir.modifies, req)
+	return nil
+}
+
+func (s *fakeSession) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if req.BaseDN == "" {
+		return &ldap.SearchResult{Entries: []*ldap.Entry{
+			ldap.NewEntry("", map[string][]string{"defaultNamingContext": {"DC=corp,DC=example"}}),
+		}}, nil
+	}
+	s.dir.filters = append(s.dir.filters, req.Filter)
+	return &ldap.SearchResult{Entries: []*ldap.Entry{ldap.NewEntry(appDN, nil)}}, nil
+}
+
+func (s *fakeSession) Close() error { return nil }
+
+func TestSetSecret_PasswordModify(t *testing.T) {
+	tests := []struct {
+		name  string
+		admin connector.Credentials
+		want  ldap.PasswordModifyRequest
+	}{
+		{
+			name:  "self",
+			admin: connector.Credentials{Username: "uid=app,ou=people,dc=example", Password: "old-pass"},
+			want:  ldap.PasswordModifyRequest{OldPassword: "old-pass", NewPassword: "new-pass"},
+		},
+		{
+			name:  "delegated admin",
+			admin: connector.Credentials{Username: "cn=admin,dc=example", Password: "admin-pass"},
+			want:  ldap.PasswordModifyRequest{UserIdentity: "uid=app,ou=people,dc=example", NewPassword: "new-pass"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := &fakeDirectory{passwords: map[string]string{tt.admin.Username: tt.admin.Password}}
+			c := &Connector{dial: dir.dial}
+			pending := connector.Credentials{Engine: EngineLDAP, Username: "uid=app,ou=people,dc=example", Password: "new-pass"}
+
+			if err := c.SetSecret(context.Background(), tt.admin, pending); err != nil {
+				t.Fatalf("SetSecret() error: %v", err)
+			}
+			if len(dir.pwModify) != 1 || !reflect.DeepEqual(*dir.pwModify[0], tt.want) {
+				t.Errorf("password modify requests = %+v, want %+v", dir.pwModify, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetSecret_ActiveDirectory(t *testing.T) {
+	tests := []struct {
+		name       string
+		admin      connector.Credentials
+		pending    string
+		wantFilter string
+		wantOps    []string
+	}{
+		{
+			name:    "self with distinguished name",
+			admin:   connector.Credentials{Username: appDN, Password: "old-pass", TLS: true},
+			pending: appDN,
+			wantOps: []string{"delete", "add"},
+		},
+		{
+			name:       "delegated admin with user principal name",
+			admin:      connector.Credentials{Username: "admin@corp.example", Password: "admin-pass", TLS: true},
+			pending:    "app@corp.example",
+			wantFilter: "(&(objectClass=user)(|(userPrincipalName=app@corp.example)(sAMAccountName=app@corp.example)))",
+			wantOps:    []string{"replace"},
+		},
+		{
+			name:       "down-level logon name",
+			admin:      connector.Credentials{Username: `CORP\admin`, Password: "admin-pass", TLS: true},
+			pending:    `CORP\app`,
+			wantFilter: `(&(objectClass=user)(|(userPrincipalName=CORP\5capp)(sAMAccountName=app)))`,
+			wantOps:    []string{"replace"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := &fakeDirectory{passwords: map[string]string{tt.admin.Username: tt.admin.Password}}
+			c := &Connector{dial: dir.dial}
+			pending := connector.Credentials{Engine: EngineActiveDirectory, Username: tt.pending, Password: "new-pass", TLS: true}
+
+			if err := c.SetSecret(context.Background(), tt.admin, pending); err != nil {
+				t.Fatalf("SetSecret() error: %v", err)
+			}
+			if tt.wantFilter != "" && (len(dir.filters) != 1 || dir.filters[0] != tt.wantFilter) {
+				t.Errorf("search filters = %v, want %s", dir.filters, tt.wantFilter)
+			}
+			if len(dir.modifies) != 1 {
+				t.Fatalf("got %d modify requests, want 1", len(dir.modifies))
+			}
+			req := dir.modifies[0]
+			if req.DN != appDN {
+				t.Errorf("modified %s, want %s", req.DN, appDN)
+			}
+			var ops []string
+			for _, change := range req.Changes {
+				ops = append(ops, map[uint]string{ldap.AddAttribute: "add", ldap.DeleteAttribute: "delete", ldap.ReplaceAttribute: "replace"}[change.Operation])
+				if change.Modification.Type != "unicodePwd" {
+					t.Errorf("modified attribute %s, want unicodePwd", change.Modification.Type)
+				}
+			}
+			if !reflect.DeepEqual(ops, tt.wantOps) {
+				t.Errorf("operations = %v, want %v", ops, tt.wantOps)
+			}
+			if got := req.Changes[len(req.Changes)-1].Modification.Vals[0]; got != encodePassword("new-pass") {
+				t.Errorf("new unicodePwd = %q", got)
+			}
+		})
+	}
+}
+
+func TestBind_RequiresTLS(t *testing.T) {
+	tests := []struct {
+		name         string
+		dir          fakeDirectory
+		wantStartTLS int
+		wantErr      bool
+	}{
+		{name: "ldaps", dir: fakeDirectory{ldaps: true}},
+		{name: "starttls", dir: fakeDirectory{}, wantStartTLS: 1},
+		{name: "starttls refused", dir: fakeDirectory{noStartTLS: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tt.dir
+			dir.passwords = map[string]string{"uid=admin,dc=example": "admin-pass"}
+			c := &Connector{dial: dir.dial}
+			admin := connector.Credentials{Username: "uid=admin,dc=example", Password: "admin-pass"}
+			pending := connector.Credentials{Engine: EngineLDAP, Username: "uid=app,dc=example", Password: "new-pass"}
+
+			err := c.SetSecret(context.Background(), admin, pending)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if dir.startTLS != tt.wantStartTLS {
+				t.Errorf("StartTLS called %d times, want %d", dir.startTLS, tt.wantStartTLS)
+			}
+			if tt.wantErr && (dir.binds > 0 || len(dir.pwModify) > 0) {
+				t.Error("password sent without TLS")
+			}
+		})
+	}
+}
+
+func TestTestSecret(t *testing.T) {
+	dir := &fakeDirectory{passwords: map[string]string{appDN: "new-pass"}}
+	c := &Connector{dial: dir.dial}
+
+	if err := c.TestSecret(context.Background(), connector.Credentials{Username: appDN, Password: "new-pass"}); err != nil {
+		t.Errorf("TestSecret() error: %v", err)
+	}
+	err := c.TestSecret(context.Background(), connector.Credentials{Username: appDN, Password: "old-pass"})
+	if !ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		t.Errorf("TestSecret() error = %v, want invalid credentials", err)
+	}
+}
+
+func TestEncodePassword(t *testing.T) {
+	if got, want := encodePassword("pä"), "\"\x00p\x00\xe4\x00\"\x00"; got != want {
+		t.Errorf("encodePassword() = %q, want %q", got, want)
+	}
+}
+
+func TestServerURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		creds connector.Credentials
+		want  string
+	}{
+		{name: "default port", creds: connector.Credentials{Host: "dc1.corp.example"}, want: "ldap://dc1.corp.example:389"},
+		{name: "tls", creds: connector.Credentials{Host: "dc1.corp.example", TLS: true}, want: "ldaps://dc1.corp.example:636"},
+		{name: "port field", creds: connector.Credentials{Host: "ldap.internal", Port: 1389}, want: "ldap://ldap.internal:1389"},
+		{name: "uri", creds: connector.Credentials{URI: "ldaps://corp.example"}, want: "ldaps://corp.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serverURL(tt.creds); got != tt.want {
+				t.Errorf("serverURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
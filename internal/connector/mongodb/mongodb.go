@@ -0,0 +1,199 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Engine values handled by this connector. Amazon DocumentDB secrets use "mongo".
+const (
+	EngineMongo   = "mongo"
+	EngineMongoDB = "mongodb"
+)
+
+const (
+	defaultPort   = 27017
+	defaultAuthDB = "admin"
+)
+
+// errCleartext is returned before a password would be sent in a command over a plain connection.
+var errCleartext = errors.New("refusing to send a password over an unencrypted connection, set tls in the secret or the connection string")
+
+// session is the part of a client connection the connector needs.
+type session interface {
+	RunCommand(ctx context.Context, db string, cmd bson.D) (bson.Raw, error)
+	Disconnect(ctx context.Context) error
+}
+
+// Connector rotates MongoDB user passwords with updateUser. Users are looked up in their
+// authentication database: dbname when the secret has one, otherwise the authSource of the
+// connection string, otherwise admin.
+//
+// updateUser carries the new password in the clear, so SetSecret requires TLS, set with the
+// tls field or in the connection string. Logins use SCRAM and do not reveal the password.
+type Connector struct {
+	dial func(ctx context.Context, creds connector.Credentials) (session, error)
+}
+
+// New creates a MongoDB connector.
+func New() *Connector {
+	return &Connector{dial: dial}
+}
+
+// SetSecret logs in as admin and changes the password of the pending user, keeping the
+// SCRAM mechanisms the user already has.
+func (c *Connector) SetSecret(ctx context.Context, admin, pending connector.Credentials) error {
+	opts, err := clientOptions(pending)
+	if err != nil {
+		return err
+	}
+	db := opts.Auth.AuthSource
+	adminOpts, err := clientOptions(admin)
+	if err != nil {
+		return err
+	}
+	if adminOpts.TLSConfig == nil {
+		return errCleartext
+	}
+
+	s, err := c.dial(ctx, admin)
+	if err != nil {
+		return fmt.Errorf("failed to connect as %s: %w", admin.Username, err)
+	}
+	defer s.Disconnect(ctx)
+
+	mechanisms, err := userMechanisms(ctx, s, db, pending.Username)
+	if err != nil {
+		return err
+	}
+
+	cmd := bson.D{{Key: "updateUser", Value: pending.Username}, {Key: "pwd", Value: pending.Password}}
+	if len(mechanisms) > 0 {
+		cmd = append(cmd, bson.E{Key: "mechanisms", Value: mechanisms})
+	}
+	if _, err := s.RunCommand(ctx, db, cmd); err != nil {
+		return fmt.Errorf("failed to update user %s: %w", pending.Username, err)
+	}
+	return nil
+}
+
+// TestSecret logs in with the pending credentials and pings the authentication database.
+func (c *Connector) TestSecret(ctx context.Context, pending connector.Credentials) error {
+	opts, err := clientOptions(pending)
+	if err != nil {
+		return err
+	}
+
+	s, err := c.dial(ctx, pending)
+	if err != nil {
+		return fmt.Errorf("failed to connect as %s: %w", pending.Username, err)
+	}
+	defer s.Disconnect(ctx)
+
+	if _, err := s.RunCommand(ctx, opts.Auth.AuthSource, bson.D{{Key: "ping", Value: 1}}); err != nil {
+		return fmt.Errorf("failed to authenticate as %s: %w", pending.Username, err)
+	}
+	return nil
+}
+
+// userMechanisms returns the SCRAM mechanisms of a user. Users authenticated outside
+// MongoDB, such as x.509 or LDAP users, have no password to rotate. Servers that do not
+// report mechanisms, such as DocumentDB, return none.
+func userMechanisms(ctx context.Context, s session, db, username string) ([]string, error) {
+	raw, err := s.RunCommand(ctx, db, bson.D{{Key: "usersInfo", Value: bson.D{
+		{Key: "user", Value: username}, {Key: "db", Value: db},
+	}}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user %s: %w", username, err)
+	}
+
+	var reply struct {
+		Users []struct {
+			Mechanisms []string `bson:"mechanisms"`
+		} `bson:"users"`
+	}
+	if err := bson.Unmarshal(raw, &reply); err != nil {
+		return nil, fmt.Errorf("failed to look up user %s: %w", username, err)
+	}
+	if len(reply.Users) == 0 {
+		return nil, fmt.Errorf("user %s does not exist in database %s", username, db)
+	}
+
+	mechanisms := reply.Users[0].Mechanisms
+	for _, m := range mechanisms {
+		if !strings.HasPrefix(m, "SCRAM-") {
+			return nil, fmt.Errorf("user %s uses %s, which does not authenticate with a password", username, m)
+		}
+	}
+	return mechanisms, nil
+}
+
+// clientOptions builds the client settings. A connection string in the secret is used as is,
+// so replica sets and TLS settings can be configured there. ParseCredentials keeps passwords out
+// of it, and the username and password fields always take precedence.
+func clientOptions(creds connector.Credentials) (*options.ClientOptions, error) {
+	opts := options.Client()
+	authDB := ""
+	if creds.URI != "" {
+		opts.ApplyURI(creds.URI)
+		if opts.Auth != nil {
+			authDB = opts.Auth.AuthSource
+		}
+	} else {
+		port := defaultPort
+		if creds.Port != 0 {
+			port = creds.Port
+		}
+		opts.SetHosts([]string{net.JoinHostPort(creds.Host, strconv.Itoa(port))})
+	}
+	if creds.TLS && opts.TLSConfig == nil {
+		opts.SetTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	switch {
+	case creds.DBName != "":
+		authDB = creds.DBName
+	case authDB == "":
+		authDB = defaultAuthDB
+	}
+	opts.SetAuth(options.Credential{AuthSource: authDB, Username: creds.Username, Password: creds.Password})
+
+	if err := opts.Validate(); err != nil {
+		return nil, errors.New("invalid connection settings: " + connector.Redact(err.Error(), creds.Password))
+	}
+	return opts, nil
+}
+
+type mongoSession struct {
+	client *mongo.Client
+}
+
+func dial(ctx context.Context, creds connector.Credentials) (session, error) {
+	opts, err := clientOptions(creds)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &mongoSession{client: client}, nil
+}
+
+func (s *mongoSession) RunCommand(ctx context.Context, db string, cmd bson.D) (bson.Raw, error) {
+	return s.client.Database(db).RunCommand(ctx, cmd).Raw()
+}
+
+func (s *mongoSession) Disconnect(ctx context.Context) error {
+	return s.client.Disconnect(ctx)
+}
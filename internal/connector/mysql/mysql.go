@@ -0,0 +1,344 @@
+package mysql
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/go-sql-driver/mysql"
+)
+
+// Engine values handled by this connector.
+const (
+	EngineMySQL   = "mysql"
+	EngineMariaDB = "mariadb"
+)
+
+const defaultPort = 3306
+
+// ephemeralHost is the host of users created by CreateUser.
+const ephemeralHost = "%"
+
+// passwordPlugins lists the authentication plugins that store a password. Accounts using any
+// other plugin, such as auth_socket or AWSAuthenticationPlugin, have no password to rotate.
+var passwordPlugins = map[string]bool{
+	"mysql_native_password": true,
+	"caching_sha2_password": true,
+	"sha256_password":       true,
+	"ed25519":               true, // MariaDB
+}
+
+// errCleartext is returned before a password would be sent in a statement over a plain connection.
+var errCleartext = errors.New("refusing to send a password over an unencrypted connection, set tls in the secret")
+
+// account is one user@host entry. plugin is empty for a MySQL user changing its own password,
+// where it is not needed.
+type account struct {
+	user, host, plugin string
+}
+
+// Connector rotates MySQL and MariaDB user passwords with ALTER USER. Statements carrying a
+// password are only sent over TLS; setting require_secure_transport on the server enforces
+// the same for every other client.
+type Connector struct {
+	open func(creds connector.Credentials) (*sql.DB, error)
+}
+
+// New creates a MySQL/MariaDB connector.
+func New() *Connector {
+	return &Connector{open: open}
+}
+
+// SetSecret logs in as admin and changes the password of every account of the pending user,
+// keeping the authentication plugin of each account.
+func (c *Connector) SetSecret(ctx context.Context, admin, pending connector.Credentials) error {
+	if !admin.TLS {
+		return errCleartext
+	}
+	db, err := c.open(admin)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	mariaDB, err := isMariaDB(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to connect as %s: %w", admin.Username, err)
+	}
+
+	accounts, err := findAccounts(ctx, db, admin.Username, pending.Username, mariaDB)
+	if err != nil {
+		return err
+	}
+	for _, a := range accounts {
+		stmt, args := alterUser(a, pending.Password, mariaDB)
+		if _, err := db.ExecContext(ctx, stmt, args...); err != nil {
+			return fmt.Errorf("failed to alter user %s@%s: %w", a.user, a.host, err)
+		}
+	}
+	return nil
+}
+
+// CloneUser creates user on every host of template with the same authentication plugin and
+// privileges. An existing user is left unchanged.
+func (c *Connector) CloneUser(ctx context.Context, admin connector.Credentials, template string, user connector.Credentials) error {
+	if !admin.TLS {
+		return errCleartext
+	}
+	db, err := c.open(admin)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	mariaDB, err := isMariaDB(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to connect as %s: %w", admin.Username, err)
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM mysql.user WHERE User = ?", user.Username).Scan(&count); err != nil {
+		return fmt.Errorf("failed to look up user %s: %w", user.Username, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	templates, err := findAccounts(ctx, db, admin.Username, template, mariaDB)
+	if err != nil {
+		return err
+	}
+	for _, t := range templates {
+		grants, err := showGrants(ctx, db, t)
+		if err != nil {
+			return err
+		}
+
+		clone := account{user: user.Username, host: t.host, plugin: t.plugin}
+		stmt, args := createUser(clone, user.Password, mariaDB)
+		if _, err := db.ExecContext(ctx, stmt, args...); err != nil {
+			return fmt.Errorf("failed to create user %s@%s: %w", clone.user, clone.host, err)
+		}
+		for _, grant := range grants {
+			grant = cloneGrant(grant, t.user, clone.user)
+			if _, err := db.ExecContext(ctx, grant); err != nil {
+				return fmt.Errorf("failed to grant privileges to %s@%s: %w", clone.user, clone.host, err)
+			}
+		}
+	}
+	return nil
+}
+
+// CreateUser creates user@'%' and grants it templateRole as its default role.
+func (c *Connector) CreateUser(ctx context.Context, admin connector.Credentials, templateRole string, user connector.Credentials) error {
+	if !admin.TLS {
+		return errCleartext
+	}
+	db, err := c.open(admin)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	mariaDB, err := isMariaDB(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to connect as %s: %w", admin.Username, err)
+	}
+
+	if _, err := db.ExecContext(ctx, "CREATE USER ?@? IDENTIFIED BY ?", user.Username, ephemeralHost, user.Password); err != nil {
+		return fmt.Errorf("failed to create user %s: %w", user.Username, err)
+	}
+	role := quoteIdentifier(templateRole)
+	if _, err := db.ExecContext(ctx, "GRANT "+role+" TO ?@?", user.Username, ephemeralHost); err != nil {
+		return fmt.Errorf("failed to grant %s to %s: %w", templateRole, user.Username, err)
+	}
+	// Roles are inactive until set as default, and the two servers disagree on the syntax
+	stmt := "SET DEFAULT ROLE " + role + " TO ?@?"
+	if mariaDB {
+		stmt = "SET DEFAULT ROLE " + role + " FOR ?@?"
+	}
+	if _, err := db.ExecContext(ctx, stmt, user.Username, ephemeralHost); err != nil {
+		return fmt.Errorf("failed to set default role of %s: %w", user.Username, err)
+	}
+	return nil
+}
+
+// DropUser drops a user created by CreateUser.
+func (c *Connector) DropUser(ctx context.Context, admin connector.Credentials, username string) error {
+	db, err := c.open(admin)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, "DROP USER IF EXISTS ?@?", username, ephemeralHost); err != nil {
+		return fmt.Errorf("failed to drop user %s: %w", username, err)
+	}
+	return nil
+}
+
+// TestSecret logs in with the pending credentials.
+func (c *Connector) TestSecret(ctx context.Context, pending connector.Credentials) error {
+	db, err := c.open(pending)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to connect as %s: %w", pending.Username, err)
+	}
+	return nil
+}
+
+func isMariaDB(ctx context.Context, db *sql.DB) (bool, error) {
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		return false, err
+	}
+	return strings.Contains(version, "MariaDB"), nil
+}
+
+func showGrants(ctx context.Context, db *sql.DB, a account) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SHOW GRANTS FOR ?@?", a.user, a.host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grants of %s@%s: %w", a.user, a.host, err)
+	}
+	defer rows.Close()
+
+	var grants []string
+	for rows.Next() {
+		var grant string
+		if err := rows.Scan(&grant); err != nil {
+			return nil, fmt.Errorf("failed to read grants of %s@%s: %w", a.user, a.host, err)
+		}
+		grants = append(grants, grant)
+	}
+	return grants, rows.Err()
+}
+
+// identifiedClause matches the credentials MariaDB includes in SHOW GRANTS output.
+var identifiedClause = regexp.MustCompile(`\s+IDENTIFIED\s+(BY|VIA)\s.*$`)
+
+// cloneGrant rewrites a SHOW GRANTS line of from so it applies to to. Only the grantee after
+// TO is replaced, granted roles keep their names, and credentials are never copied.
+func cloneGrant(grant, from, to string) string {
+	grant = identifiedClause.ReplaceAllString(grant, "")
+	for _, q := range []string{"`", "'"} {
+		grant = strings.Replace(grant, " TO "+q+from+q+"@", " TO "+q+to+q+"@", 1)
+	}
+	return grant
+}
+
+// findAccounts returns the accounts to update. A user changing its own password may not be
+// allowed to read mysql.user, so it only updates the account it is logged in as. MariaDB
+// needs the plugin of that account too, see alterUser, so there it must be readable.
+func findAccounts(ctx context.Context, db *sql.DB, adminUser, username string, mariaDB bool) ([]account, error) {
+	if adminUser == username {
+		var current string
+		if err := db.QueryRowContext(ctx, "SELECT CURRENT_USER()").Scan(&current); err != nil {
+			return nil, fmt.Errorf("failed to read current user: %w", err)
+		}
+		i := strings.LastIndexByte(current, '@')
+		if i < 0 {
+			return nil, fmt.Errorf("unexpected current user: %s", current)
+		}
+		a := account{user: current[:i], host: current[i+1:]}
+		if !mariaDB {
+			return []account{a}, nil
+		}
+		// mysql.user is a view of mysql.global_priv since MariaDB 10.4
+		if err := db.QueryRowContext(ctx, "SELECT plugin FROM mysql.user WHERE User = ? AND Host = ?", a.user, a.host).Scan(&a.plugin); err != nil {
+			return nil, fmt.Errorf("failed to read authentication plugin of %s@%s, grant it SELECT on mysql.global_priv "+
+				"or rotate with an admin secret: %w", a.user, a.host, err)
+		}
+		if !passwordPlugins[a.plugin] {
+			return nil, fmt.Errorf("account %s@%s uses %s, which does not authenticate with a password", a.user, a.host, a.plugin)
+		}
+		return []account{a}, nil
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT Host, plugin FROM mysql.user WHERE User = ?", username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user %s: %w", username, err)
+	}
+	defer rows.Close()
+
+	var accounts []account
+	for rows.Next() {
+		a := account{user: username}
+		if err := rows.Scan(&a.host, &a.plugin); err != nil {
+			return nil, fmt.Errorf("failed to look up user %s: %w", username, err)
+		}
+		if !passwordPlugins[a.plugin] {
+			return nil, fmt.Errorf("account %s@%s uses %s, which does not authenticate with a password", a.user, a.host, a.plugin)
+		}
+		accounts = append(accounts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to look up user %s: %w", username, err)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("user %s does not exist", username)
+	}
+	return accounts, nil
+}
+
+// alterUser builds the statement for one account. MySQL keeps the account's plugin when none
+// is named, MariaDB falls back to mysql_native_password, so ed25519 accounts name it explicitly.
+func alterUser(a account, password string, mariaDB bool) (string, []any) {
+	if mariaDB && a.plugin == "ed25519" {
+		return "ALTER USER ?@? IDENTIFIED VIA ed25519 USING PASSWORD(?)", []any{a.user, a.host, password}
+	}
+	return "ALTER USER ?@? IDENTIFIED BY ?", []any{a.user, a.host, password}
+}
+
+// createUser builds CREATE USER for an account, naming its plugin when it is known.
+func createUser(a account, password string, mariaDB bool) (string, []any) {
+	switch {
+	case mariaDB && a.plugin == "ed25519":
+		return "CREATE USER ?@? IDENTIFIED VIA ed25519 USING PASSWORD(?)", []any{a.user, a.host, password}
+	case !mariaDB && passwordPlugins[a.plugin]:
+		// The plugin comes from the passwordPlugins allow list, so it is safe to format
+		return "CREATE USER ?@? IDENTIFIED WITH " + a.plugin + " BY ?", []any{a.user, a.host, password}
+	default:
+		return "CREATE USER ?@? IDENTIFIED BY ?", []any{a.user, a.host, password}
+	}
+}
+
+// quoteIdentifier quotes a role name. Role names cannot be passed as parameters.
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func open(creds connector.Credentials) (*sql.DB, error) {
+	port := defaultPort
+	if creds.Port != 0 {
+		port = creds.Port
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(creds.Host, strconv.Itoa(port))
+	cfg.User = creds.Username
+	cfg.Passwd = creds.Password
+	cfg.DBName = creds.DBName
+	// ALTER USER cannot be prepared, so values are escaped by the driver instead
+	cfg.InterpolateParams = true
+	if creds.TLS {
+		cfg.TLS = &tls.Config{ServerName: creds.Host, MinVersion: tls.VersionTLS12}
+	}
+
+	c, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(c), nil
+}
@@ -0,0 +1,266 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+)
+
+func newMock(t *testing.T) (*Connector, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("sqlmock.New() error: %v", err)
+	}
+	return &Connector{open: func(creds connector.Credentials) (*sql.DB, error) { return db, nil }}, mock
+}
+
+func TestSetSecret(t *testing.T) {
+	admin := connector.Credentials{Host: "db.internal", Username: "admin", Password: "admin-pass", TLS: true}
+	pending := connector.Credentials{Host: "db.internal", Username: "app", Password: "new-pass"}
+
+	tests := []struct {
+		name    string
+		admin   connector.Credentials
+		setup   func(mock sqlmock.Sqlmock)
+		wantErr string
+	}{
+		{
+			name:  "admin updates every account keeping the plugin",
+			admin: admin,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT VERSION()").WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow("8.0.36"))
+				mock.ExpectQuery("SELECT Host, plugin FROM mysql.user WHERE User = ?").WithArgs("app").
+					WillReturnRows(sqlmock.NewRows([]string{"Host", "plugin"}).
+						AddRow("%", "caching_sha2_password").
+						AddRow("localhost", "mysql_native_password"))
+				mock.ExpectExec("ALTER USER ?@? IDENTIFIED BY ?").WithArgs("app", "%", "new-pass").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("ALTER USER ?@? IDENTIFIED BY ?").WithArgs("app", "localhost", "new-pass").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name:  "MariaDB ed25519 account",
+			admin: admin,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT VERSION()").WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow("10.11.6-MariaDB"))
+				mock.ExpectQuery("SELECT Host, plugin FROM mysql.user WHERE User = ?").WithArgs("app").
+					WillReturnRows(sqlmock.NewRows([]string{"Host", "plugin"}).AddRow("%", "ed25519"))
+				mock.ExpectExec("ALTER USER ?@? IDENTIFIED VIA ed25519 USING PASSWORD(?)").WithArgs("app", "%", "new-pass").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name:  "self rotation uses the current account",
+			admin: connector.Credentials{Host: "db.internal", Username: "app", Password: "old-pass", TLS: true},
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT VERSION()").WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow("8.0.36"))
+				mock.ExpectQuery("SELECT CURRENT_USER()").WillReturnRows(sqlmock.NewRows([]string{"u"}).AddRow("app@10.0.%"))
+				mock.ExpectExec("ALTER USER ?@? IDENTIFIED BY ?").WithArgs("app", "10.0.%", "new-pass").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name:  "MariaDB self rotation keeps ed25519",
+			admin: connector.Credentials{Host: "db.internal", Username: "app", Password: "old-pass", TLS: true},
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT VERSION()").WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow("10.11.6-MariaDB"))
+				mock.ExpectQuery("SELECT CURRENT_USER()").WillReturnRows(sqlmock.NewRows([]string{"u"}).AddRow("app@%"))
+				mock.ExpectQuery("SELECT plugin FROM mysql.user WHERE User = ? AND Host = ?").WithArgs("app", "%").
+					WillReturnRows(sqlmock.NewRows([]string{"plugin"}).AddRow("ed25519"))
+				mock.ExpectExec("ALTER USER ?@? IDENTIFIED VIA ed25519 USING PASSWORD(?)").WithArgs("app", "%", "new-pass").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name:  "MariaDB self rotation without access to the plugin",
+			admin: connector.Credentials{Host: "db.internal", Username: "app", Password: "old-pass", TLS: true},
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT VERSION()").WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow("10.11.6-MariaDB"))
+				mock.ExpectQuery("SELECT CURRENT_USER()").WillReturnRows(sqlmock.NewRows([]string{"u"}).AddRow("app@%"))
+				mock.ExpectQuery("SELECT plugin FROM mysql.user WHERE User = ? AND Host = ?").WithArgs("app", "%").
+					WillReturnError(errors.New("SELECT command denied to user 'app'@'%' for table 'user'"))
+			},
+			wantErr: "failed to read authentication plugin of app@%",
+		},
+
+		{
+			name:  "plugin without password",
+			admin: admin,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT VERSION()").WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow("8.0.36"))
+				mock.ExpectQuery("SELECT Host, plugin FROM mysql.user WHERE User = ?").WithArgs("app").
+					WillReturnRows(sqlmock.NewRows([]string{"Host", "plugin"}).AddRow("%", "AWSAuthenticationPlugin"))
+			},
+			wantErr: "does not authenticate with a password",
+		},
+		{
+			name:  "unknown user",
+			admin: admin,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT VERSION()").WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow("8.0.36"))
+				mock.ExpectQuery("SELECT Host, plugin FROM mysql.user WHERE User = ?").WithArgs("app").
+					WillReturnRows(sqlmock.NewRows([]string{"Host", "plugin"}))
+			},
+			wantErr: "does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mock := newMock(t)
+			tt.setup(mock)
+			mock.ExpectClose()
+
+			err := c.SetSecret(context.Background(), tt.admin, pending)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("SetSecret() error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("SetSecret() error = %v, want %q", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetSecret_RequiresTLS(t *testing.T) {
+	c := &Connector{open: func(creds connector.Credentials) (*sql.DB, error) {
+		t.Fatal("connected without TLS")
+		return nil, nil
+	}}
+	admin := connector.Credentials{Host: "db.internal", Username: "admin", Password: "admin-pass"}
+	pending := connector.Credentials{Host: "db.internal", Username: "app", Password: "new-pass"}
+
+	if err := c.SetSecret(context.Background(), admin, pending); !errors.Is(err, errCleartext) {
+		t.Errorf("SetSecret() error = %v, want %v", err, errCleartext)
+	}
+}
+
+func TestTestSecret(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock.New() error: %v", err)
+	}
+	var opened connector.Credentials
+	c := &Connector{open: func(creds connector.Credentials) (*sql.DB, error) {
+		opened = creds
+		return db, nil
+	}}
+	mock.ExpectPing()
+	mock.ExpectClose()
+
+	pending := connector.Credentials{Host: "db.internal", Username: "app", Password: "new-pass"}
+	if err := c.TestSecret(context.Background(), pending); err != nil {
+		t.Fatalf("TestSecret() error: %v", err)
+	}
+	if opened != pending {
+		t.Errorf("opened %+v, want pending credentials", opened)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestCloneUser(t *testing.T) {
+	admin := connector.Credentials{Host: "db.internal", Username: "admin", Password: "admin-pass", TLS: true}
+	user := connector.Credentials{Host: "db.internal", Username: "app_clone", Password: "new-pass"}
+
+	c, mock := newMock(t)
+	mock.ExpectQuery("SELECT VERSION()").WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow("8.0.36"))
+	mock.ExpectQuery("SELECT COUNT(*) FROM mysql.user WHERE User = ?").WithArgs("app_clone").
+		WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(0))
+	mock.ExpectQuery("SELECT Host, plugin FROM mysql.user WHERE User = ?").WithArgs("app").
+		WillReturnRows(sqlmock.NewRows([]string{"Host", "plugin"}).AddRow("%", "caching_sha2_password"))
+	mock.ExpectQuery("SHOW GRANTS FOR ?@?").WithArgs("app", "%").
+		WillReturnRows(sqlmock.NewRows([]string{"g"}).
+			AddRow("GRANT USAGE ON *.* TO `app`@`%`").
+			AddRow("GRANT SELECT, INSERT ON `orders`.* TO `app`@`%`").
+			AddRow("GRANT `reporting`@`%` TO `app`@`%`"))
+	mock.ExpectExec("CREATE USER ?@? IDENTIFIED WITH caching_sha2_password BY ?").WithArgs("app_clone", "%", "new-pass").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("GRANT USAGE ON *.* TO `app_clone`@`%`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("GRANT SELECT, INSERT ON `orders`.* TO `app_clone`@`%`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("GRANT `reporting`@`%` TO `app_clone`@`%`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectClose()
+
+	if err := c.CloneUser(context.Background(), admin, "app", user); err != nil {
+		t.Fatalf("CloneUser() error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestCloneGrant(t *testing.T) {
+	tests := []struct {
+		grant, want string
+	}{
+		{"GRANT SELECT ON `app`.* TO `app`@`%`", "GRANT SELECT ON `app`.* TO `app_clone`@`%`"},
+		{"GRANT USAGE ON *.* TO 'app'@'localhost' IDENTIFIED BY PASSWORD '*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19'", "GRANT USAGE ON *.* TO 'app_clone'@'localhost'"},
+	}
+	for _, tt := range tests {
+		if got := cloneGrant(tt.grant, "app", "app_clone"); got != tt.want {
+			t.Errorf("cloneGrant(%q) = %q, want %q", tt.grant, got, tt.want)
+		}
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	admin := connector.Credentials{Host: "db.internal", Username: "admin", Password: "admin-pass", TLS: true}
+	user := connector.Credentials{Host: "db.internal", Username: "app_20261019120000_ab12", Password: "new-pass"}
+
+	tests := []struct {
+		name       string
+		version    string
+		defaultSQL string
+	}{
+		{name: "mysql", version: "8.0.36", defaultSQL: "SET DEFAULT ROLE `app_reader` TO ?@?"},
+		{name: "mariadb", version: "10.11.6-MariaDB", defaultSQL: "SET DEFAULT ROLE `app_reader` FOR ?@?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mock := newMock(t)
+			mock.ExpectQuery("SELECT VERSION()").WillReturnRows(sqlmock.NewRows([]string{"v"}).AddRow(tt.version))
+			mock.ExpectExec("CREATE USER ?@? IDENTIFIED BY ?").WithArgs(user.Username, "%", "new-pass").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("GRANT `app_reader` TO ?@?").WithArgs(user.Username, "%").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(tt.defaultSQL).WithArgs(user.Username, "%").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectClose()
+
+			if err := c.CreateUser(context.Background(), admin, "app_reader", user); err != nil {
+				t.Fatalf("CreateUser() error: %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestDropUser(t *testing.T) {
+	admin := connector.Credentials{Host: "db.internal", Username: "admin", Password: "admin-pass", TLS: true}
+
+	c, mock := newMock(t)
+	mock.ExpectExec("DROP USER IF EXISTS ?@?").WithArgs("app_20261017120000_cd34", "%").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectClose()
+
+	if err := c.DropUser(context.Background(), admin, "app_20261017120000_cd34"); err != nil {
+		t.Fatalf("DropUser() error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
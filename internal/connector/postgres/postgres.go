@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/darthlynx/secret-rotation-lambda/internal/hasher"
+	"github.com/jackc/pgx/v5"
+)
+
+// Engine is the secret engine value handled by this connector.
+const Engine = "postgres"
+
+const (
+	defaultPort     = 5432
+	defaultDatabase = "postgres"
+)
+
+// session is the part of a database connection the connector needs.
+type session interface {
+	Exec(ctx context.Context, sql string) error
+	// Exists reports whether the query returns at least one row.
+	Exists(ctx context.Context, sql string, args ...any) (bool, error)
+	Close(ctx context.Context) error
+}
+
+// Connector rotates PostgreSQL role passwords. The password is sent as a SCRAM-SHA-256
+// verifier, so the plaintext never reaches the server or its statement logs.
+type Connector struct {
+	dial func(ctx context.Context, creds connector.Credentials) (session, error)
+}
+
+// New creates a PostgreSQL connector.
+func New() *Connector {
+	return &Connector{dial: dial}
+}
+
+// SetSecret logs in as admin and runs ALTER ROLE for the pending user.
+func (c *Connector) SetSecret(ctx context.Context, admin, pending connector.Credentials) error {
+	stmt, err := alterRolePassword(pending.Username, pending.Password)
+	if err != nil {
+		return err
+	}
+
+	s, err := c.dial(ctx, admin)
+	if err != nil {
+		return fmt.Errorf("failed to connect as %s: %w", admin.Username, err)
+	}
+	defer s.Close(ctx)
+
+	if err := s.Exec(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to alter role %s: %w", pending.Username, err)
+	}
+	return nil
+}
+
+// TestSecret logs in with the pending credentials.
+func (c *Connector) TestSecret(ctx context.Context, pending connector.Credentials) error {
+	s, err := c.dial(ctx, pending)
+	if err != nil {
+		return fmt.Errorf("failed to connect as %s: %w", pending.Username, err)
+	}
+	defer s.Close(ctx)
+
+	return s.Exec(ctx, "SELECT 1")
+}
+
+// CloneUser creates user as a login role that is a member of template, so it inherits the
+// template's privileges. An existing role is left unchanged.
+func (c *Connector) CloneUser(ctx context.Context, admin connector.Credentials, template string, user connector.Credentials) error {
+	s, err := c.dial(ctx, admin)
+	if err != nil {
+		return fmt.Errorf("failed to connect as %s: %w", admin.Username, err)
+	}
+	defer s.Close(ctx)
+
+	exists, err := s.Exists(ctx, "SELECT 1 FROM pg_roles WHERE rolname = $1", user.Username)
+	if err != nil {
+		return fmt.Errorf("failed to look up role %s: %w", user.Username, err)
+	}
+	if exists {
+		return nil
+	}
+	return createRole(ctx, s, template, user)
+}
+
+// CreateUser creates user as a login role that is a member of templateRole. Unlike
+// CloneUser it fails if the role already exists.
+func (c *Connector) CreateUser(ctx context.Context, admin connector.Credentials, templateRole string, user connector.Credentials) error {
+	s, err := c.dial(ctx, admin)
+	if err != nil {
+		return fmt.Errorf("failed to connect as %s: %w", admin.Username, err)
+	}
+	defer s.Close(ctx)
+
+	return createRole(ctx, s, templateRole, user)
+}
+
+// DropUser drops a role created by CreateUser. Open sessions of the role are not terminated.
+func (c *Connector) DropUser(ctx context.Context, admin connector.Credentials, username string) error {
+	s, err := c.dial(ctx, admin)
+	if err != nil {
+		return fmt.Errorf("failed to connect as %s: %w", admin.Username, err)
+	}
+	defer s.Close(ctx)
+
+	if err := s.Exec(ctx, "DROP ROLE IF EXISTS "+pgx.Identifier{username}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to drop role %s: %w", username, err)
+	}
+	return nil
+}
+
+func createRole(ctx context.Context, s session, template string, user connector.Credentials) error {
+	verifier, err := hasher.SCRAMSHA256(user.Password)
+	if err != nil {
+		return fmt.Errorf("failed to compute SCRAM verifier: %w", err)
+	}
+	stmt := fmt.Sprintf("CREATE ROLE %s WITH LOGIN PASSWORD %s IN ROLE %s",
+		pgx.Identifier{user.Username}.Sanitize(), quoteLiteral(verifier), pgx.Identifier{template}.Sanitize())
+	if err := s.Exec(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create role %s: %w", user.Username, err)
+	}
+	return nil
+}
+
+func alterRolePassword(role, password string) (string, error) {
+	verifier, err := hasher.SCRAMSHA256(password)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute SCRAM verifier: %w", err)
+	}
+	return fmt.Sprintf("ALTER ROLE %s PASSWORD %s", pgx.Identifier{role}.Sanitize(), quoteLiteral(verifier)), nil
+}
+
+// quoteLiteral quotes a string constant. ALTER ROLE does not accept bind parameters.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// connConfig builds the connection settings. Other settings such as sslmode come from
+// the standard PG* environment variables.
+func connConfig(creds connector.Credentials) (*pgx.ConnConfig, error) {
+	cfg, err := pgx.ParseConfig("")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Host = creds.Host
+	cfg.Port = defaultPort
+	if creds.Port != 0 {
+		cfg.Port = uint16(creds.Port)
+	}
+	cfg.User = creds.Username
+	cfg.Password = creds.Password
+	cfg.Database = defaultDatabase
+	if creds.DBName != "" {
+		cfg.Database = creds.DBName
+	}
+	return cfg, nil
+}
+
+type pgxSession struct {
+	conn *pgx.Conn
+}
+
+func dial(ctx context.Context, creds connector.Credentials) (session, error) {
+	cfg, err := connConfig(creds)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &pgxSession{conn: conn}, nil
+}
+
+func (s *pgxSession) Exec(ctx context.Context, sql string) error {
+	_, err := s.conn.Exec(ctx, sql)
+	return err
+}
+
+func (s *pgxSession) Exists(ctx context.Context, sql string, args ...any) (bool, error) {
+	var one int
+	err := s.conn.QueryRow(ctx, sql, args...).Scan(&one)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *pgxSession) Close(ctx context.Context) error {
+	return s.conn.Close(ctx)
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+)
+
+type fakeSession struct {
+	stmts  []string
+	err    error
+	exists bool
+	closed bool
+}
+
+func (s *fakeSession) Exec(ctx context.Context, sql string) error {
+	s.stmts = append(s.stmts, sql)
+	return s.err
+}
+
+func (s *fakeSession) Exists(ctx context.Context, sql string, args ...any) (bool, error) {
+	s.stmts = append(s.stmts, sql)
+	return s.exists, nil
+}
+
+func (s *fakeSession) Close(ctx context.Context) error {
+	s.closed = true
+	return nil
+}
+
+func TestSetSecret(t *testing.T) {
+	s := &fakeSession{}
+	var dialed connector.Credentials
+	c := &Connector{dial: func(ctx context.Context, creds connector.Credentials) (session, error) {
+		dialed = creds
+		return s, nil
+	}}
+
+	admin := connector.Credentials{Host: "db.internal", Username: "postgres", Password: "admin-pass"}
+	pending := connector.Credentials{Host: "db.internal", Username: `app"user`, Password: "n3w'pass"}
+	if err := c.SetSecret(context.Background(), admin, pending); err != nil {
+		t.Fatalf("SetSecret() error: %v", err)
+	}
+
+	if dialed != admin {
+		t.Errorf("dialed %+v, want admin credentials", dialed)
+	}
+	if len(s.stmts) != 1 {
+		t.Fatalf("statements = %v, want one", s.stmts)
+	}
+	stmt := s.stmts[0]
+	pattern := regexp.MustCompile(`^ALTER ROLE "app""user" PASSWORD 'SCRAM-SHA-256\$4096:[A-Za-z0-9+/=]+\$[A-Za-z0-9+/=]+:[A-Za-z0-9+/=]+'$`)
+	if !pattern.MatchString(stmt) {
+		t.Errorf("statement = %q, want ALTER ROLE with SCRAM verifier", stmt)
+	}
+	if strings.Contains(stmt, "n3w") {
+		t.Errorf("statement contains the plaintext password: %q", stmt)
+	}
+	if !s.closed {
+		t.Errorf("session was not closed")
+	}
+}
+
+func TestSetSecret_Error(t *testing.T) {
+	c := &Connector{dial: func(ctx context.Context, creds connector.Credentials) (session, error) {
+		return &fakeSession{err: errors.New("permission denied")}, nil
+	}}
+	err := c.SetSecret(context.Background(), connector.Credentials{Username: "app"}, connector.Credentials{Username: "app", Password: "p"})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("SetSecret() error = %v, want permission denied", err)
+	}
+}
+
+func TestTestSecret(t *testing.T) {
+	s := &fakeSession{}
+	var dialed connector.Credentials
+	c := &Connector{dial: func(ctx context.Context, creds connector.Credentials) (session, error) {
+		dialed = creds
+		return s, nil
+	}}
+
+	pending := connector.Credentials{Host: "db.internal", Username: "app", Password: "new"}
+	if err := c.TestSecret(context.Background(), pending); err != nil {
+		t.Fatalf("TestSecret() error: %v", err)
+	}
+	if dialed != pending || len(s.stmts) != 1 || s.stmts[0] != "SELECT 1" {
+		t.Errorf("dialed %+v with %v, want pending credentials and SELECT 1", dialed, s.stmts)
+	}
+}
+
+func TestConnConfig(t *testing.T) {
+	cfg, err := connConfig(connector.Credentials{Host: "db.internal", Username: "app", Password: "p"})
+	if err != nil {
+		t.Fatalf("connConfig() error: %v", err)
+	}
+	if cfg.Host != "db.internal" || cfg.Port != defaultPort || cfg.Database != defaultDatabase || cfg.User != "app" || cfg.Password != "p" {
+		t.Errorf("unexpected config: host=%s port=%d db=%s user=%s", cfg.Host, cfg.Port, cfg.Database, cfg.User)
+	}
+
+	cfg, _ = connConfig(connector.Credentials{Host: "db.internal", Port: 6432, Username: "app", DBName: "orders"})
+	if cfg.Port != 6432 || cfg.Database != "orders" {
+		t.Errorf("unexpected config: port=%d db=%s", cfg.Port, cfg.Database)
+	}
+}
+
+func TestCloneUser(t *testing.T) {
+	admin := connector.Credentials{Host: "db.internal", Username: "postgres", Password: "admin-pass"}
+	user := connector.Credentials{Host: "db.internal", Username: "app_user_clone", Password: "new-pass"}
+
+	s := &fakeSession{}
+	c := &Connector{dial: func(ctx context.Context, creds connector.Credentials) (session, error) { return s, nil }}
+	if err := c.CloneUser(context.Background(), admin, "app_user", user); err != nil {
+		t.Fatalf("CloneUser() error: %v", err)
+	}
+	if len(s.stmts) != 2 {
+		t.Fatalf("statements = %v, want lookup and CREATE ROLE", s.stmts)
+	}
+	pattern := regexp.MustCompile(`^CREATE ROLE "app_user_clone" WITH LOGIN PASSWORD 'SCRAM-SHA-256\$[^']+' IN ROLE "app_user"$`)
+	if !pattern.MatchString(s.stmts[1]) {
+		t.Errorf("statement = %q, want CREATE ROLE ... IN ROLE", s.stmts[1])
+	}
+
+	existing := &fakeSession{exists: true}
+	c = &Connector{dial: func(ctx context.Context, creds connector.Credentials) (session, error) { return existing, nil }}
+	if err := c.CloneUser(context.Background(), admin, "app_user", user); err != nil {
+		t.Fatalf("CloneUser() error: %v", err)
+	}
+	if len(existing.stmts) != 1 {
+		t.Errorf("existing role was modified: %v", existing.stmts)
+	}
+}
+
+func TestCreateAndDropUser(t *testing.T) {
+	admin := connector.Credentials{Host: "db.internal", Username: "postgres", Password: "admin-pass"}
+	user := connector.Credentials{Host: "db.internal", Username: "app_20261019120000_ab12", Password: "new-pass"}
+
+	s := &fakeSession{}
+	c := &Connector{dial: func(ctx context.Context, creds connector.Credentials) (session, error) { return s, nil }}
+	if err := c.CreateUser(context.Background(), admin, "app_reader", user); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+	if err := c.DropUser(context.Background(), admin, "app_20261017120000_cd34"); err != nil {
+		t.Fatalf("DropUser() error: %v", err)
+	}
+	if len(s.stmts) != 2 {
+		t.Fatalf("statements = %v, want CREATE ROLE and DROP ROLE", s.stmts)
+	}
+	pattern := regexp.MustCompile(`^CREATE ROLE "app_20261019120000_ab12" WITH LOGIN PASSWORD 'SCRAM-SHA-256\$[^']+' IN ROLE "app_reader"$`)
+	if !pattern.MatchString(s.stmts[0]) {
+		t.Errorf("statement = %q, want CREATE ROLE ... IN ROLE", s.stmts[0])
+	}
+	if want := `DROP ROLE IF EXISTS "app_20261017120000_cd34"`; s.stmts[1] != want {
+		t.Errorf("statement = %q, want %q", s.stmts[1], want)
+	}
+}
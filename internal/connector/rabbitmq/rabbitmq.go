@@ -0,0 +1,194 @@
+package rabbitmq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+)
+
+// Engine is the secret engine value handled by this connector.
+const Engine = "rabbitmq"
+
+// defaultTLSPort is the default https port of the management API. The port field of a secret
+// usually names the AMQP port used by applications, so it is not used for the management API.
+const defaultTLSPort = 15671
+
+// errCleartext is returned before a password would be sent over plain HTTP.
+var errCleartext = errors.New("refusing to send passwords to a management API over plain HTTP, use an https management_url or set tls in the secret")
+
+// notManagementUser is the reason given when valid credentials lack a management tag.
+const notManagementUser = "Not management user"
+
+// Connector rotates RabbitMQ user passwords through the management HTTP API. The API is
+// reached at the management_url field of the secret when it is set, for example Amazon MQ's
+// https://b-1234.mq.us-east-1.amazonaws.com, otherwise on the host at the default port. The
+// uri field is left to applications, it usually holds an amqp:// connection string.
+//
+// Every request carries a password in its basic auth header, so the API is only used over https.
+type Connector struct {
+	client *http.Client
+}
+
+// New creates a RabbitMQ connector.
+func New() *Connector {
+	return &Connector{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// user is the part of a management API user the connector reads and writes.
+type user struct {
+	Password string `json:"password,omitempty"`
+	Tags     tags   `json:"tags"`
+}
+
+// tags are returned as a list by RabbitMQ 3.9 and later and as a comma separated string by
+// older versions. They are always written as a string, which every version accepts.
+type tags []string
+
+func (t *tags) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*t = list
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*t = nil
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+func (t tags) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.Join(t, ","))
+}
+
+// SetSecret logs in as admin and changes the password of the pending user. PUT replaces the
+// whole user, so the current tags are read first and written back unchanged.
+func (c *Connector) SetSecret(ctx context.Context, admin, pending connector.Credentials) error {
+	path := "/api/users/" + url.PathEscape(pending.Username)
+
+	var current user
+	if err := c.do(ctx, admin, http.MethodGet, path, nil, &current); err != nil {
+		return fmt.Errorf("failed to read user %s: %w", pending.Username, err)
+	}
+
+	updated := user{Password: pending.Password, Tags: current.Tags}
+	if err := c.do(ctx, admin, http.MethodPut, path, updated, nil); err != nil {
+		return fmt.Errorf("failed to update user %s: %w", pending.Username, err)
+	}
+	return nil
+}
+
+// TestSecret authenticates with the pending credentials. Users without a management tag may
+// not use the API, but RabbitMQ only reports that once their password has been accepted.
+func (c *Connector) TestSecret(ctx context.Context, pending connector.Credentials) error {
+	var whoami struct {
+		Name string `json:"name"`
+	}
+	err := c.do(ctx, pending, http.MethodGet, "/api/whoami", nil, &whoami)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Reason == notManagementUser {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to authenticate as %s: %w", pending.Username, err)
+	}
+	if whoami.Name != pending.Username {
+		return fmt.Errorf("authenticated as %s instead of %s", whoami.Name, pending.Username)
+	}
+	return nil
+}
+
+// apiError is an error response of the management API.
+type apiError struct {
+	Status int
+	Reason string
+}
+
+func (e *apiError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("management API returned %d", e.Status)
+	}
+	return fmt.Sprintf("management API returned %d: %s", e.Status, e.Reason)
+}
+
+func (c *Connector) do(ctx context.Context, creds connector.Credentials, method, path string, in, out any) error {
+	base, err := baseURL(creds)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, base+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(creds.Username, creds.Password)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Reason string `json:"reason"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return &apiError{Status: resp.StatusCode, Reason: e.Reason}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// baseURL returns the https address of the management API without a trailing slash.
+func baseURL(creds connector.Credentials) (string, error) {
+	if creds.ManagementURL != "" {
+		u, err := url.Parse(creds.ManagementURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("invalid %s: must be an http or https URL", connector.FieldManagementURL)
+		}
+		if u.Scheme != "https" {
+			return "", errCleartext
+		}
+		u.User = nil
+		return strings.TrimSuffix(u.String(), "/"), nil
+	}
+
+	if !creds.TLS {
+		return "", errCleartext
+	}
+	return "https://" + net.JoinHostPort(creds.Host, strconv.Itoa(defaultTLSPort)), nil
+}
@@ -0,0 +1,177 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+)
+
+// fakeAPI is a stand-in for the management API with users, their passwords and tags.
+type fakeAPI struct {
+	mu        sync.Mutex
+	passwords map[string]string
+	tags      map[string]json.RawMessage
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	username, password, ok := r.BasicAuth()
+	if !ok || f.passwords[username] != password {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"not_authorized","reason":"Login failed"}`))
+		return
+	}
+	if string(f.tags[username]) == `""` {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"not_authorized","reason":"Not management user"}`))
+		return
+	}
+
+	name, isUser := strings.CutPrefix(r.URL.Path, "/api/users/")
+	switch {
+	case r.URL.Path == "/api/whoami" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": username, "tags": f.tags[username]})
+	case isUser && f.passwords[name] == "":
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"Object Not Found","reason":"Not Found"}`))
+	case isUser && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": name, "tags": f.tags[name]})
+	case isUser && r.Method == http.MethodPut:
+		var body struct {
+			Password string          `json:"password"`
+			Tags     json.RawMessage `json:"tags"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Tags == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.passwords[name], f.tags[name] = body.Password, body.Tags
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// newFakeAPI starts the API over https and returns a connector trusting it.
+func newFakeAPI(t *testing.T, appTags string) (*fakeAPI, string, *Connector) {
+	t.Helper()
+	f := &fakeAPI{
+		passwords: map[string]string{"admin": "admin-pass", "app": "old-pass"},
+		tags:      map[string]json.RawMessage{"admin": json.RawMessage(`["administrator"]`), "app": json.RawMessage(appTags)},
+	}
+	srv := httptest.NewTLSServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv.URL, &Connector{client: srv.Client()}
+}
+
+func TestRotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     string
+		wantTags string
+	}{
+		{name: "tag list", tags: `["monitoring","policymaker"]`, wantTags: `"monitoring,policymaker"`},
+		{name: "tag string", tags: `"monitoring"`, wantTags: `"monitoring"`},
+		{name: "user without management tag", tags: `""`, wantTags: `""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, uri, c := newFakeAPI(t, tt.tags)
+			admin := connector.Credentials{ManagementURL: uri, Username: "admin", Password: "admin-pass"}
+			pending := connector.Credentials{ManagementURL: uri, Username: "app", Password: "new-pass"}
+
+			if err := c.SetSecret(context.Background(), admin, pending); err != nil {
+				t.Fatalf("SetSecret() error: %v", err)
+			}
+			if f.passwords["app"] != "new-pass" {
+				t.Errorf("password = %q, want new-pass", f.passwords["app"])
+			}
+			if string(f.tags["app"]) != tt.wantTags {
+				t.Errorf("tags = %s, want %s", f.tags["app"], tt.wantTags)
+			}
+			if err := c.TestSecret(context.Background(), pending); err != nil {
+				t.Errorf("TestSecret() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetSecret_MissingUser(t *testing.T) {
+	_, uri, c := newFakeAPI(t, `[]`)
+	admin := connector.Credentials{ManagementURL: uri, Username: "admin", Password: "admin-pass"}
+
+	err := c.SetSecret(context.Background(), admin, connector.Credentials{ManagementURL: uri, Username: "typo", Password: "p"})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("SetSecret() error = %v, want 404", err)
+	}
+}
+
+func TestTestSecret_WrongPassword(t *testing.T) {
+	_, uri, c := newFakeAPI(t, `""`)
+
+	err := c.TestSecret(context.Background(), connector.Credentials{ManagementURL: uri, Username: "app", Password: "wrong"})
+	if err == nil || !strings.Contains(err.Error(), "Login failed") {
+		t.Errorf("TestSecret() error = %v, want login failure", err)
+	}
+}
+
+func TestRequiresHTTPS(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { requests++ }))
+	defer srv.Close()
+	admin := connector.Credentials{ManagementURL: srv.URL, Username: "admin", Password: "admin-pass"}
+	pending := connector.Credentials{ManagementURL: srv.URL, Username: "app", Password: "new-pass"}
+
+	c := New()
+	if err := c.SetSecret(context.Background(), admin, pending); !errors.Is(err, errCleartext) {
+		t.Errorf("SetSecret() error = %v, want %v", err, errCleartext)
+	}
+	if err := c.TestSecret(context.Background(), pending); !errors.Is(err, errCleartext) {
+		t.Errorf("TestSecret() error = %v, want %v", err, errCleartext)
+	}
+	if requests != 0 {
+		t.Errorf("%d requests sent over plain HTTP", requests)
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		creds   connector.Credentials
+		want    string
+		wantErr bool
+	}{
+		{name: "host without tls", creds: connector.Credentials{Host: "mq.internal", Port: 5672}, wantErr: true},
+		{name: "tls", creds: connector.Credentials{Host: "mq.internal", Port: 5671, TLS: true}, want: "https://mq.internal:15671"},
+		{name: "management url", creds: connector.Credentials{ManagementURL: "https://user:pw@b-1.mq.amazonaws.com/"}, want: "https://b-1.mq.amazonaws.com"},
+		{name: "http management url", creds: connector.Credentials{ManagementURL: "http://mq.internal:15672"}, wantErr: true},
+		{name: "amqp management url", creds: connector.Credentials{ManagementURL: "amqps://b-1.mq.amazonaws.com:5671"}, wantErr: true},
+		{
+			name:  "amqp uri is left to applications",
+			creds: connector.Credentials{Host: "mq.internal", URI: "amqps://mq.internal:5671", TLS: true},
+			want:  "https://mq.internal:15671",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := baseURL(tt.creds)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("baseURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("baseURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/redis/go-redis/v9"
+)
+
+// Engine is the secret engine value handled by this connector.
+const Engine = "redis"
+
+const defaultPort = 6379
+
+// errCleartext is returned before a password would be sent in an ACL command over a plain connection.
+var errCleartext = errors.New("refusing to send a password over an unencrypted connection, set tls in the secret")
+
+// Connector rotates Redis 6+ ACL user passwords. The new password is added next to the old
+// one, which is only removed by FinishSecret, so clients holding the old password keep
+// working until the new version is promoted.
+//
+// ACL commands carry passwords in the clear, so they are only sent over TLS. TestSecret only
+// logs in, as the applications using the secret do.
+//
+// ACL changes apply to the node the connector talks to. Users defined in an ACL file must be
+// saved with ACL SAVE to survive a restart.
+type Connector struct {
+	rootCAs *x509.CertPool // nil uses the system roots
+}
+
+// New creates a Redis connector.
+func New() *Connector {
+	return &Connector{}
+}
+
+// SetSecret logs in as admin and adds the pending password to the user.
+func (c *Connector) SetSecret(ctx context.Context, admin, pending connector.Credentials) error {
+	if !admin.TLS {
+		return errCleartext
+	}
+	client := c.newClient(admin)
+	defer client.Close()
+
+	// ACL SETUSER creates missing users, so a typo in the secret would add a new user
+	if err := client.Do(ctx, "ACL", "GETUSER", pending.Username).Err(); errors.Is(err, redis.Nil) {
+		return fmt.Errorf("user %s does not exist", pending.Username)
+	} else if err != nil {
+		return fmt.Errorf("failed to look up user %s: %w", pending.Username, err)
+	}
+
+	if err := client.Do(ctx, "ACL", "SETUSER", pending.Username, ">"+pending.Password).Err(); err != nil {
+		return fmt.Errorf("failed to add password of user %s: %w", pending.Username, err)
+	}
+	return nil
+}
+
+// TestSecret authenticates with the pending credentials.
+func (c *Connector) TestSecret(ctx context.Context, pending connector.Credentials) error {
+	client := c.newClient(pending)
+	defer client.Close()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to authenticate as %s: %w", pending.Username, err)
+	}
+	return nil
+}
+
+// FinishSecret removes the previous password once the pending one has been promoted.
+func (c *Connector) FinishSecret(ctx context.Context, admin, pending, previous connector.Credentials) error {
+	if previous.Username != pending.Username || previous.Password == "" || previous.Password == pending.Password {
+		return nil
+	}
+
+	if !admin.TLS {
+		return errCleartext
+	}
+	client := c.newClient(admin)
+	defer client.Close()
+
+	if err := client.Do(ctx, "ACL", "SETUSER", pending.Username, "<"+previous.Password).Err(); err != nil {
+		return fmt.Errorf("failed to remove previous password of user %s: %w", pending.Username, err)
+	}
+	return nil
+}
+
+// RevertSecret removes the pending password after a failed rotation. The previous password
+// was never removed, so it keeps working.
+func (c *Connector) RevertSecret(ctx context.Context, admin, pending, previous connector.Credentials) error {
+	if previous.Username != pending.Username || previous.Password == pending.Password {
+		return nil
+	}
+
+	if !admin.TLS {
+		return errCleartext
+	}
+	client := c.newClient(admin)
+	defer client.Close()
+
+	if err := client.Do(ctx, "ACL", "SETUSER", pending.Username, "<"+pending.Password).Err(); err != nil {
+		return fmt.Errorf("failed to remove pending password of user %s: %w", pending.Username, err)
+	}
+	return nil
+}
+
+// newClient creates a single-connection client. The client authenticates with HELLO, or
+// AUTH on servers without it, before its first command.
+func (c *Connector) newClient(creds connector.Credentials) *redis.Client {
+	port := defaultPort
+	if creds.Port != 0 {
+		port = creds.Port
+	}
+
+	opts := &redis.Options{
+		Addr:            net.JoinHostPort(creds.Host, strconv.Itoa(port)),
+		Username:        creds.Username,
+		Password:        creds.Password,
+		PoolSize:        1,
+		MaxRetries:      -1,
+		DisableIdentity: true,
+	}
+	if creds.TLS {
+		opts.TLSConfig = &tls.Config{ServerName: creds.Host, RootCAs: c.rootCAs, MinVersion: tls.VersionTLS12}
+	}
+	return redis.NewClient(opts)
+}
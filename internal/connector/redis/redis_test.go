@@ -0,0 +1,285 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+)
+
+// fakeServer speaks enough RESP2 over TLS to cover AUTH and ACL users. Like Redis without
+// HELLO support, it rejects HELLO so clients fall back to AUTH.
+type fakeServer struct {
+	mu    sync.Mutex
+	users map[string]map[string]bool // user -> passwords
+	addr  *net.TCPAddr
+	roots *x509.CertPool
+}
+
+func newFakeServer(t *testing.T, users map[string][]string) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	cert, roots := selfSignedCert(t)
+
+	s := &fakeServer{users: map[string]map[string]bool{}, addr: ln.Addr().(*net.TCPAddr), roots: roots}
+	ln = tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{cert}})
+	for user, passwords := range users {
+		s.users[user] = map[string]bool{}
+		for _, p := range passwords {
+			s.users[user][p] = true
+		}
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 and a pool trusting it.
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "redis.test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, roots
+}
+
+func (s *fakeServer) creds(username, password string) connector.Credentials {
+	return connector.Credentials{Host: "127.0.0.1", Port: s.addr.Port, Username: username, Password: password, TLS: true}
+}
+
+// connector returns a connector trusting the server certificate.
+func (s *fakeServer) connector() *Connector {
+	return &Connector{rootCAs: s.roots}
+}
+
+func (s *fakeServer) hasPassword(user, password string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users[user][password]
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authenticated := false
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		io.WriteString(conn, s.handle(args, &authenticated))
+	}
+}
+
+func (s *fakeServer) handle(args []string, authenticated *bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cmd := strings.ToUpper(args[0])
+	if cmd == "ACL" && len(args) > 1 {
+		cmd += " " + strings.ToUpper(args[1])
+	}
+	switch {
+	case cmd == "AUTH" && len(args) == 3:
+		if !s.users[args[1]][args[2]] {
+			return "-WRONGPASS invalid username-password pair or user is disabled.\r\n"
+		}
+		*authenticated = true
+		return "+OK\r\n"
+	case !*authenticated:
+		return "-NOAUTH Authentication required.\r\n"
+	case cmd == "PING":
+		return "+PONG\r\n"
+	case cmd == "ACL GETUSER":
+		if _, ok := s.users[args[2]]; !ok {
+			return "$-1\r\n"
+		}
+		return "*0\r\n"
+	case cmd == "ACL SETUSER":
+		passwords := s.users[args[2]]
+		if passwords == nil {
+			passwords = map[string]bool{}
+			s.users[args[2]] = passwords
+		}
+		for _, rule := range args[3:] {
+			switch rule[0] {
+			case '>':
+				passwords[rule[1:]] = true
+			case '<':
+				delete(passwords, rule[1:])
+			}
+		}
+		return "+OK\r\n"
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil { // $<length>
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestRotation(t *testing.T) {
+	s := newFakeServer(t, map[string][]string{"admin": {"admin-pass"}, "app": {"old-pass"}})
+	c := s.connector()
+	ctx := context.Background()
+	admin := s.creds("admin", "admin-pass")
+	pending := s.creds("app", "new pass")
+	previous := s.creds("app", "old-pass")
+
+	if err := c.SetSecret(ctx, admin, pending); err != nil {
+		t.Fatalf("SetSecret() error: %v", err)
+	}
+	if !s.hasPassword("app", "new pass") || !s.hasPassword("app", "old-pass") {
+		t.Fatalf("both passwords must be valid until FinishSecret: %v", s.users["app"])
+	}
+	if err := c.TestSecret(ctx, pending); err != nil {
+		t.Fatalf("TestSecret() error: %v", err)
+	}
+	if err := c.FinishSecret(ctx, admin, pending, previous); err != nil {
+		t.Fatalf("FinishSecret() error: %v", err)
+	}
+	if !s.hasPassword("app", "new pass") || s.hasPassword("app", "old-pass") {
+		t.Errorf("only the new password must remain: %v", s.users["app"])
+	}
+}
+
+func TestRevertSecret(t *testing.T) {
+	s := newFakeServer(t, map[string][]string{"app": {"old-pass"}})
+	c := s.connector()
+	ctx := context.Background()
+	previous := s.creds("app", "old-pass")
+	pending := s.creds("app", "new-pass")
+
+	if err := c.SetSecret(ctx, previous, pending); err != nil {
+		t.Fatalf("SetSecret() error: %v", err)
+	}
+	if err := c.RevertSecret(ctx, previous, pending, previous); err != nil {
+		t.Fatalf("RevertSecret() error: %v", err)
+	}
+	if s.hasPassword("app", "new-pass") || !s.hasPassword("app", "old-pass") {
+		t.Errorf("passwords = %v, want only old-pass", s.users["app"])
+	}
+}
+
+func TestSetSecret_SelfRotation(t *testing.T) {
+	s := newFakeServer(t, map[string][]string{"app": {"old-pass"}})
+	c := s.connector()
+	current := s.creds("app", "old-pass")
+
+	if err := c.SetSecret(context.Background(), current, s.creds("app", "new-pass")); err != nil {
+		t.Fatalf("SetSecret() error: %v", err)
+	}
+	if err := c.FinishSecret(context.Background(), current, s.creds("app", "new-pass"), current); err != nil {
+		t.Fatalf("FinishSecret() error: %v", err)
+	}
+	if !s.hasPassword("app", "new-pass") || s.hasPassword("app", "old-pass") {
+		t.Errorf("passwords = %v, want only new-pass", s.users["app"])
+	}
+}
+
+func TestSetSecret_MissingUser(t *testing.T) {
+	s := newFakeServer(t, map[string][]string{"admin": {"admin-pass"}})
+
+	err := s.connector().SetSecret(context.Background(), s.creds("admin", "admin-pass"), s.creds("typo", "new-pass"))
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("SetSecret() error = %v, want missing user", err)
+	}
+	if _, ok := s.users["typo"]; ok {
+		t.Error("missing user was created")
+	}
+}
+
+func TestTestSecret_WrongPassword(t *testing.T) {
+	s := newFakeServer(t, map[string][]string{"app": {"old-pass"}})
+
+	if err := s.connector().TestSecret(context.Background(), s.creds("app", "new-pass")); err == nil {
+		t.Fatal("TestSecret() succeeded with a wrong password")
+	}
+}
+
+func TestRequiresTLS(t *testing.T) {
+	s := newFakeServer(t, map[string][]string{"app": {"old-pass"}})
+	c := s.connector()
+	ctx := context.Background()
+	admin := s.creds("app", "old-pass")
+	admin.TLS = false
+	pending := s.creds("app", "new-pass")
+
+	for name, err := range map[string]error{
+		"SetSecret":    c.SetSecret(ctx, admin, pending),
+		"RevertSecret": c.RevertSecret(ctx, admin, pending, s.creds("app", "old-pass")),
+		"FinishSecret": c.FinishSecret(ctx, admin, pending, s.creds("app", "old-pass")),
+	} {
+		if !errors.Is(err, errCleartext) {
+			t.Errorf("%s() error = %v, want %v", name, err, errCleartext)
+		}
+	}
+	if s.hasPassword("app", "new-pass") {
+		t.Error("password sent without TLS")
+	}
+}
@@ -0,0 +1,153 @@
+package fileformat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var dotenvEntry = regexp.MustCompile(`^(\s*(?:export\s+)?)([A-Za-z_][A-Za-z0-9_.-]*)(\s*=\s*)(.*)$`)
+
+type dotenvValue struct {
+	line   int
+	prefix string // indentation, export, key and separator
+	quote  byte   // 0, '\'' or '"'
+	value  string
+	suffix string // whitespace and inline comment after the value
+}
+
+type dotenvDoc struct {
+	lines    []string
+	trailing bool
+	entries  map[string]*dotenvValue
+	order    []string
+}
+
+func parseDotenv(text string) (*dotenvDoc, error) {
+	doc := &dotenvDoc{entries: map[string]*dotenvValue{}}
+	doc.lines, doc.trailing = splitLines(text)
+
+	for i, line := range doc.lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		m := dotenvEntry.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: not a KEY=VALUE entry", i+1)
+		}
+		entry := &dotenvValue{line: i, prefix: m[1] + m[2] + m[3]}
+		if err := entry.parseValue(m[4]); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if _, dup := doc.entries[m[2]]; !dup {
+			doc.order = append(doc.order, m[2])
+		}
+		// Like most dotenv loaders, the last definition wins
+		doc.entries[m[2]] = entry
+	}
+	return doc, nil
+}
+
+func (e *dotenvValue) parseValue(raw string) error {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			switch raw[i] {
+			case '\\':
+				if i+1 < len(raw) {
+					i++
+					switch raw[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						b.WriteByte(raw[i])
+					}
+				}
+			case '"':
+				e.quote, e.value, e.suffix = '"', b.String(), raw[i+1:]
+				return nil
+			default:
+				b.WriteByte(raw[i])
+			}
+		}
+		return fmt.Errorf("unterminated double quoted value")
+	case strings.HasPrefix(raw, "'"):
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return fmt.Errorf("unterminated single quoted value")
+		}
+		e.quote, e.value, e.suffix = '\'', raw[1:end+1], raw[end+2:]
+	default:
+		value := raw
+		if i := strings.Index(raw, " #"); i >= 0 {
+			value = raw[:i]
+		}
+		value = strings.TrimRight(value, " \t")
+		e.value, e.suffix = value, raw[len(value):]
+	}
+	return nil
+}
+
+func (e *dotenvValue) render() string {
+	quote := e.quote
+	switch {
+	case quote == 0 && strings.ContainsAny(e.value, " \t#\"'\\$`") || quote == 0 && e.value == "":
+		quote = '\''
+		if strings.Contains(e.value, "'") {
+			quote = '"'
+		}
+	case quote == '\'' && strings.Contains(e.value, "'"):
+		quote = '"'
+	case quote == '"' && strings.ContainsAny(e.value, "$`") && !strings.Contains(e.value, "'"):
+		// Single quotes stop loaders from expanding variables inside the value
+		quote = '\''
+	}
+
+	var value string
+	switch quote {
+	case '\'':
+		value = "'" + e.value + "'"
+	case '"':
+		value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`, "`", "\\`").Replace(e.value) + `"`
+	default:
+		value = e.value
+	}
+
+	return e.prefix + value + e.suffix
+}
+
+func (d *dotenvDoc) Keys() []string {
+	return append([]string(nil), d.order...)
+}
+
+func (d *dotenvDoc) Get(key string) (string, bool) {
+	e, ok := d.entries[key]
+	if !ok {
+		return "", false
+	}
+	return e.value, true
+}
+
+func (d *dotenvDoc) Set(key, value string) error {
+	e, ok := d.entries[key]
+	if !ok {
+		if !dotenvEntry.MatchString(key + "=") {
+			return fmt.Errorf("invalid dotenv key: %s", key)
+		}
+		e = &dotenvValue{line: len(d.lines), prefix: key + "="}
+		d.lines = append(d.lines, "")
+		d.entries[key] = e
+		d.order = append(d.order, key)
+	}
+	e.value = value
+	d.lines[e.line] = e.render()
+	return nil
+}
+
+func (d *dotenvDoc) Render() (string, error) {
+	return joinLines(d.lines, d.trailing), nil
+}
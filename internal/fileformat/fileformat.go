@@ -0,0 +1,59 @@
+package fileformat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Supported file formats.
+const (
+	FormatDotenv     = "dotenv"
+	FormatYAML       = "yaml"
+	FormatINI        = "ini"
+	FormatProperties = "properties"
+)
+
+// Document is a parsed configuration file whose values can be replaced without
+// touching comments, ordering or the quoting of other entries.
+type Document interface {
+	// Keys returns the keys in file order. Nested keys are joined with ".".
+	Keys() []string
+	Get(key string) (string, bool)
+	// Set replaces the value of key, or appends the key if it does not exist yet.
+	Set(key, value string) error
+	// Render serializes the document back to text.
+	Render() (string, error)
+}
+
+// Parse reads text in the given format.
+func Parse(format, text string) (Document, error) {
+	switch format {
+	case FormatDotenv:
+		return parseDotenv(text)
+	case FormatYAML:
+		return parseYAML(text)
+	case FormatINI:
+		return parseINI(text)
+	case FormatProperties:
+		return parseProperties(text)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", format)
+	}
+}
+
+// splitLines splits text into lines and reports whether it ended with a newline.
+func splitLines(text string) ([]string, bool) {
+	if text == "" {
+		return nil, true
+	}
+	trailing := strings.HasSuffix(text, "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), trailing
+}
+
+func joinLines(lines []string, trailing bool) string {
+	out := strings.Join(lines, "\n")
+	if trailing && len(lines) > 0 {
+		out += "\n"
+	}
+	return out
+}
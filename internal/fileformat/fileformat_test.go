@@ -0,0 +1,350 @@
+package fileformat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDotenv(t *testing.T) {
+	input := `# database settings
+export DB_USER=app
+DB_PASSWORD="old-pass" # rotated monthly
+API_KEY='old-key'
+PLAIN=old   # trailing comment
+
+EMPTY=
+`
+	doc, err := Parse(FormatDotenv, input)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	wantKeys := []string{"DB_USER", "DB_PASSWORD", "API_KEY", "PLAIN", "EMPTY"}
+	if got := doc.Keys(); !reflect.DeepEqual(got, wantKeys) {
+		t.Errorf("Keys() = %v, want %v", got, wantKeys)
+	}
+	if v, _ := doc.Get("DB_PASSWORD"); v != "old-pass" {
+		t.Errorf("Get(DB_PASSWORD) = %q, want old-pass", v)
+	}
+
+	mustSet(t, doc, "DB_PASSWORD", `n"ew\pass`)
+	mustSet(t, doc, "API_KEY", "it's$new")
+	mustSet(t, doc, "PLAIN", "has space")
+	mustSet(t, doc, "NEW_KEY", "value")
+
+	want := `# database settings
+export DB_USER=app
+DB_PASSWORD="n\"ew\\pass" # rotated monthly
+API_KEY="it's\$new"
+PLAIN='has space'   # trailing comment
+
+EMPTY=
+NEW_KEY=value
+`
+	assertRender(t, doc, want)
+
+	reparsed, err := Parse(FormatDotenv, want)
+	if err != nil {
+		t.Fatalf("Parse() of rendered document error: %v", err)
+	}
+	for key, value := range map[string]string{"DB_PASSWORD": `n"ew\pass`, "API_KEY": "it's$new", "PLAIN": "has space"} {
+		if got, _ := reparsed.Get(key); got != value {
+			t.Errorf("round trip %s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestDotenv_Invalid(t *testing.T) {
+	for _, input := range []string{"not an entry", `KEY="unterminated`} {
+		if _, err := Parse(FormatDotenv, input); err == nil {
+			t.Errorf("Parse(%q) expected error, got nil", input)
+		}
+	}
+}
+
+func TestProperties(t *testing.T) {
+	input := `# Spring settings
+! legacy comment
+spring.datasource.username = app
+spring.datasource.password=old
+multi.line = first \
+    second
+key\ with\ spaces : value
+`
+	doc, err := Parse(FormatProperties, input)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	wantKeys := []string{"spring.datasource.username", "spring.datasource.password", "multi.line", "key with spaces"}
+	if got := doc.Keys(); !reflect.DeepEqual(got, wantKeys) {
+		t.Errorf("Keys() = %v, want %v", got, wantKeys)
+	}
+	if v, _ := doc.Get("multi.line"); v != "first second" {
+		t.Errorf("Get(multi.line) = %q, want %q", v, "first second")
+	}
+
+	mustSet(t, doc, "spring.datasource.password", `#n=w:p\ss`)
+	mustSet(t, doc, "multi.line", "collapsed")
+	mustSet(t, doc, "key with spaces", "ünïcode")
+	mustSet(t, doc, "new.key", "v")
+
+	want := `# Spring settings
+! legacy comment
+spring.datasource.username = app
+spring.datasource.password=\#n=w:p\\ss
+multi.line = collapsed
+key\ with\ spaces : \u00fcn\u00efcode
+new.key=v
+`
+	assertRender(t, doc, want)
+
+	reparsed, _ := Parse(FormatProperties, want)
+	if v, _ := reparsed.Get("spring.datasource.password"); v != `#n=w:p\ss` {
+		t.Errorf("round trip password = %q", v)
+	}
+	if v, _ := reparsed.Get("key with spaces"); v != "ünïcode" {
+		t.Errorf("round trip unicode = %q", v)
+	}
+}
+
+func TestINI(t *testing.T) {
+	input := `; global settings
+log_level = info
+
+[database]
+user = app
+password = "old pass" ; quoted
+
+[cache]
+token: old
+`
+	doc, err := Parse(FormatINI, input)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	wantKeys := []string{"log_level", "database.user", "database.password", "cache.token"}
+	if got := doc.Keys(); !reflect.DeepEqual(got, wantKeys) {
+		t.Errorf("Keys() = %v, want %v", got, wantKeys)
+	}
+
+	mustSet(t, doc, "database.password", "new;pass")
+	mustSet(t, doc, "cache.token", "t0ken")
+	mustSet(t, doc, "database.host", "db.internal")
+	mustSet(t, doc, "queue.secret", "s3cret")
+
+	want := `; global settings
+log_level = info
+
+[database]
+user = app
+password = "new;pass" ; quoted
+host = db.internal
+
+[cache]
+token: t0ken
+
+[queue]
+secret = s3cret
+`
+	assertRender(t, doc, want)
+}
+
+func TestINI_RoundTrip(t *testing.T) {
+	input := `[database]
+password = old
+token = "old" ; quoted
+`
+	doc, err := Parse(FormatINI, input)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	values := map[string]string{
+		"database.password": `p;a#s"s\`,
+		"database.token":    `"quoted"`,
+	}
+	for key, value := range values {
+		mustSet(t, doc, key, value)
+	}
+
+	want := `[database]
+password = "p;a#s\"s\\"
+token = "\"quoted\"" ; quoted
+`
+	assertRender(t, doc, want)
+
+	reparsed, err := Parse(FormatINI, want)
+	if err != nil {
+		t.Fatalf("Parse() of rendered document error: %v", err)
+	}
+	for key, value := range values {
+		if got, _ := reparsed.Get(key); got != value {
+			t.Errorf("round trip %s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestYAML(t *testing.T) {
+	input := `# application secrets
+database:
+  user: app
+  password: 'old' # rotated
+  port: 5432
+api_key: "old-key"
+hosts:
+  - a
+  - b
+`
+	doc, err := Parse(FormatYAML, input)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	wantKeys := []string{"database.user", "database.password", "database.port", "api_key"}
+	if got := doc.Keys(); !reflect.DeepEqual(got, wantKeys) {
+		t.Errorf("Keys() = %v, want %v", got, wantKeys)
+	}
+
+	mustSet(t, doc, "database.password", "new: pass")
+	mustSet(t, doc, "api_key", "true")
+	mustSet(t, doc, "cache.token", "12345")
+
+	want := `# application secrets
+database:
+  user: app
+  password: 'new: pass' # rotated
+  port: 5432
+api_key: "true"
+hosts:
+  - a
+  - b
+cache:
+  token: "12345"
+`
+	assertRender(t, doc, want)
+}
+
+func TestYAML_PreservesLayout(t *testing.T) {
+	input := `# indented with four spaces
+
+database:
+    user:   app      # aligned
+    password: &pw old-pass
+
+    options: {sslmode: require, timeout: 5}
+    note: |
+        first line
+        second line
+    empty:
+replica_password: *pw
+description: a plain value
+    spanning two lines
+`
+	doc, err := Parse(FormatYAML, input)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	mustSet(t, doc, "database.user", "svc")
+	mustSet(t, doc, "database.password", "n#w pass")
+	mustSet(t, doc, "database.options.timeout", "10")
+	mustSet(t, doc, "database.options.token", "a,b")
+	mustSet(t, doc, "database.note", "single")
+	mustSet(t, doc, "database.empty", "filled")
+	mustSet(t, doc, "database.pool.size", "4")
+	mustSet(t, doc, "description", "short")
+
+	want := `# indented with four spaces
+
+database:
+    user:   svc      # aligned
+    password: &pw n#w pass
+
+    options: {sslmode: require, timeout: "10", token: "a,b"}
+    note: single
+    empty: filled
+    pool:
+        size: "4"
+replica_password: *pw
+description: short
+`
+	assertRender(t, doc, want)
+
+	reparsed, err := Parse(FormatYAML, want)
+	if err != nil {
+		t.Fatalf("Parse() of rendered document error: %v", err)
+	}
+	if v, _ := reparsed.Get("database.password"); v != "n#w pass" {
+		t.Errorf("round trip password = %q", v)
+	}
+}
+
+func TestYAML_DottedKeys(t *testing.T) {
+	input := `spring.datasource.password: old
+spring:
+  datasource.username: app
+  mail:
+    password: old-mail
+`
+	doc, err := Parse(FormatYAML, input)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	wantKeys := []string{"spring.datasource.password", "spring.datasource.username", "spring.mail.password"}
+	if got := doc.Keys(); !reflect.DeepEqual(got, wantKeys) {
+		t.Errorf("Keys() = %v, want %v", got, wantKeys)
+	}
+	for _, key := range wantKeys {
+		if v, ok := doc.Get(key); !ok || v == "" {
+			t.Errorf("Get(%s) = %q, %v", key, v, ok)
+		}
+	}
+
+	mustSet(t, doc, "spring.datasource.password", "new")
+	mustSet(t, doc, "spring.datasource.username", "svc")
+	mustSet(t, doc, "spring.mail.password", "new-mail")
+	mustSet(t, doc, "spring.mail.host", "smtp.internal")
+
+	want := `spring.datasource.password: new
+spring:
+  datasource.username: svc
+  mail:
+    password: new-mail
+    host: smtp.internal
+`
+	assertRender(t, doc, want)
+
+	if err := doc.Set("spring.datasource.username.extra", "x"); err == nil {
+		t.Errorf("Set() below a scalar expected error, got nil")
+	}
+}
+
+func TestParse_UnsupportedFormat(t *testing.T) {
+	if _, err := Parse("toml", ""); err == nil {
+		t.Errorf("Parse() expected error, got nil")
+	}
+}
+
+func mustSet(t *testing.T, doc Document, key, value string) {
+	t.Helper()
+	if err := doc.Set(key, value); err != nil {
+		t.Fatalf("Set(%s) error: %v", key, err)
+	}
+	if got, _ := doc.Get(key); got != value {
+		t.Errorf("Get(%s) after Set = %q, want %q", key, got, value)
+	}
+}
+
+func assertRender(t *testing.T, doc Document, want string) {
+	t.Helper()
+	got, err := doc.Render()
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+	if got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+}
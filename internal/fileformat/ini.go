@@ -0,0 +1,181 @@
+package fileformat
+
+import (
+	"fmt"
+	"strings"
+)
+
+type iniValue struct {
+	line   int
+	prefix string // indentation, key and separator
+	quoted bool
+	value  string
+	suffix string // text after a closing quote
+}
+
+// iniDoc addresses keys as "section.key", keys before the first section have no prefix.
+type iniDoc struct {
+	lines    []string
+	trailing bool
+	entries  map[string]*iniValue
+	order    []string
+	sections map[string]int // section -> last line belonging to it
+}
+
+func parseINI(text string) (*iniDoc, error) {
+	doc := &iniDoc{entries: map[string]*iniValue{}, sections: map[string]int{}}
+	doc.lines, doc.trailing = splitLines(text)
+
+	section := ""
+	for i, line := range doc.lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == ';' || trimmed[0] == '#' {
+			continue
+		}
+		if trimmed[0] == '[' {
+			end := strings.IndexByte(trimmed, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated section header", i+1)
+			}
+			section = strings.TrimSpace(trimmed[1:end])
+			doc.sections[section] = i
+			continue
+		}
+
+		sep := strings.IndexAny(line, "=:")
+		if sep < 0 {
+			return nil, fmt.Errorf("line %d: not a key=value entry", i+1)
+		}
+		key := strings.TrimSpace(line[:sep])
+		valueStart := sep + 1
+		for valueStart < len(line) && (line[valueStart] == ' ' || line[valueStart] == '\t') {
+			valueStart++
+		}
+
+		entry := &iniValue{line: i, prefix: line[:valueStart]}
+		raw := line[valueStart:]
+		if strings.HasPrefix(raw, `"`) {
+			if value, end, ok := unquoteINI(raw); ok {
+				entry.quoted, entry.value, entry.suffix = true, value, raw[end:]
+			}
+		}
+		if !entry.quoted {
+			entry.value = strings.TrimRight(raw, " \t")
+			entry.suffix = raw[len(entry.value):]
+		}
+
+		path := joinINIKey(section, key)
+		if _, dup := doc.entries[path]; !dup {
+			doc.order = append(doc.order, path)
+		}
+		doc.entries[path] = entry
+		doc.sections[section] = i
+	}
+	return doc, nil
+}
+
+func joinINIKey(section, key string) string {
+	if section == "" {
+		return key
+	}
+	return section + "." + key
+}
+
+// unquoteINI reads the double-quoted value at the start of raw, where \" and \\ stand for
+// a quote and a backslash. It returns the value and the index just past the closing quote.
+func unquoteINI(raw string) (string, int, bool) {
+	var b strings.Builder
+	for i := 1; i < len(raw); i++ {
+		switch raw[i] {
+		case '"':
+			return b.String(), i + 1, true
+		case '\\':
+			if i+1 < len(raw) && (raw[i+1] == '"' || raw[i+1] == '\\') {
+				i++
+			}
+		}
+		b.WriteByte(raw[i])
+	}
+	return "", 0, false
+}
+
+func (e *iniValue) render() string {
+	value := e.value
+	// Unquoted, most parsers would cut the value at a comment character or trim its spaces
+	if e.quoted || strings.TrimSpace(value) != value || strings.ContainsAny(value, `;#"`) {
+		value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+	return e.prefix + value + e.suffix
+}
+
+func (d *iniDoc) Keys() []string {
+	return append([]string(nil), d.order...)
+}
+
+func (d *iniDoc) Get(key string) (string, bool) {
+	e, ok := d.entries[key]
+	if !ok {
+		return "", false
+	}
+	return e.value, true
+}
+
+func (d *iniDoc) Set(key, value string) error {
+	e, ok := d.entries[key]
+	if !ok {
+		var err error
+		if e, err = d.insert(key); err != nil {
+			return err
+		}
+	}
+	e.value = value
+	d.lines[e.line] = e.render()
+	return nil
+}
+
+// insert adds an empty entry after the last line of its section, creating the section if needed.
+func (d *iniDoc) insert(key string) (*iniValue, error) {
+	section, name := "", key
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		section, name = key[:i], key[i+1:]
+	}
+	if name == "" || strings.ContainsAny(name, "=:[]") {
+		return nil, fmt.Errorf("invalid ini key: %s", key)
+	}
+
+	last, ok := d.sections[section]
+	if !ok {
+		if section == "" {
+			last = -1
+		} else {
+			if len(d.lines) > 0 {
+				d.lines = append(d.lines, "")
+			}
+			d.lines = append(d.lines, "["+section+"]")
+			last = len(d.lines) - 1
+		}
+	}
+
+	at := last + 1
+	d.lines = append(d.lines[:at], append([]string{""}, d.lines[at:]...)...)
+	for _, e := range d.entries {
+		if e.line >= at {
+			e.line++
+		}
+	}
+	for s, l := range d.sections {
+		if l >= at {
+			d.sections[s] = l + 1
+		}
+	}
+
+	e := &iniValue{line: at, prefix: name + " = "}
+	d.entries[key] = e
+	d.order = append(d.order, key)
+	d.sections[section] = at
+	return e, nil
+}
+
+func (d *iniDoc) Render() (string, error) {
+	return joinLines(d.lines, d.trailing), nil
+}
@@ -0,0 +1,215 @@
+package fileformat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// propertiesEntry is one logical line, which may span several physical lines through continuations.
+type propertiesEntry struct {
+	key    string
+	prefix string // raw key and separator as written
+	value  string
+}
+
+type propertiesDoc struct {
+	chunks   [][]string // physical lines grouped per logical line
+	trailing bool
+	entries  map[string]int // key -> chunk index
+	values   map[int]*propertiesEntry
+	order    []string
+}
+
+func parseProperties(text string) (*propertiesDoc, error) {
+	doc := &propertiesDoc{entries: map[string]int{}, values: map[int]*propertiesEntry{}}
+	var lines []string
+	lines, doc.trailing = splitLines(text)
+
+	for i := 0; i < len(lines); i++ {
+		chunk := []string{lines[i]}
+		trimmed := strings.TrimLeft(lines[i], " \t\f")
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+			doc.chunks = append(doc.chunks, chunk)
+			continue
+		}
+		for continues(chunk[len(chunk)-1]) && i+1 < len(lines) {
+			i++
+			chunk = append(chunk, lines[i])
+		}
+
+		entry := parsePropertiesEntry(chunk)
+		index := len(doc.chunks)
+		doc.chunks = append(doc.chunks, chunk)
+		if _, dup := doc.entries[entry.key]; !dup {
+			doc.order = append(doc.order, entry.key)
+		}
+		doc.entries[entry.key] = index
+		doc.values[index] = entry
+	}
+	return doc, nil
+}
+
+// continues reports whether a line ends with an odd number of backslashes.
+func continues(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+func parsePropertiesEntry(chunk []string) *propertiesEntry {
+	// Join the logical line, dropping continuation backslashes and leading whitespace of continued lines
+	logical := chunk[0]
+	for _, next := range chunk[1:] {
+		logical = logical[:len(logical)-1] + strings.TrimLeft(next, " \t\f")
+	}
+	if continues(logical) {
+		logical = logical[:len(logical)-1]
+	}
+
+	start := len(logical) - len(strings.TrimLeft(logical, " \t\f"))
+	i := start
+	for i < len(logical) {
+		c := logical[i]
+		if c == '\\' {
+			i += 2
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			break
+		}
+		i++
+	}
+	if i > len(logical) {
+		i = len(logical)
+	}
+	keyEnd := i
+
+	// Separator: optional whitespace, at most one '=' or ':', optional whitespace
+	for i < len(logical) && strings.IndexByte(" \t\f", logical[i]) >= 0 {
+		i++
+	}
+	if i < len(logical) && (logical[i] == '=' || logical[i] == ':') {
+		i++
+	}
+	for i < len(logical) && strings.IndexByte(" \t\f", logical[i]) >= 0 {
+		i++
+	}
+
+	return &propertiesEntry{
+		key:    unescapeProperties(logical[start:keyEnd]),
+		prefix: logical[:i],
+		value:  unescapeProperties(logical[i:]),
+	}
+}
+
+func unescapeProperties(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					i += 4
+					// Characters outside the BMP are written as a surrogate pair
+					if utf16.IsSurrogate(rune(r)) && i+6 < len(s) && s[i+1:i+3] == `\u` {
+						if low, err := strconv.ParseUint(s[i+3:i+7], 16, 32); err == nil {
+							b.WriteRune(utf16.DecodeRune(rune(r), rune(low)))
+							i += 6
+							continue
+						}
+					}
+					b.WriteRune(rune(r))
+					continue
+				}
+			}
+			b.WriteByte('u')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// escapeProperties escapes a value so it reads back unchanged, using \uXXXX for non-ASCII.
+func escapeProperties(s string, isKey bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && (isKey || i == 0):
+			b.WriteString(`\ `)
+		case isKey && (r == '=' || r == ':') || (r == '#' || r == '!') && i == 0:
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, u := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04x`, u)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (d *propertiesDoc) Keys() []string {
+	return append([]string(nil), d.order...)
+}
+
+func (d *propertiesDoc) Get(key string) (string, bool) {
+	index, ok := d.entries[key]
+	if !ok {
+		return "", false
+	}
+	return d.values[index].value, true
+}
+
+func (d *propertiesDoc) Set(key, value string) error {
+	index, ok := d.entries[key]
+	if !ok {
+		index = len(d.chunks)
+		d.chunks = append(d.chunks, nil)
+		d.entries[key] = index
+		d.values[index] = &propertiesEntry{key: key, prefix: escapeProperties(key, true) + "="}
+		d.order = append(d.order, key)
+	}
+	entry := d.values[index]
+	entry.value = value
+	// A continued value is collapsed onto one line
+	d.chunks[index] = []string{entry.prefix + escapeProperties(value, false)}
+	return nil
+}
+
+func (d *propertiesDoc) Render() (string, error) {
+	var lines []string
+	for _, chunk := range d.chunks {
+		lines = append(lines, chunk...)
+	}
+	return joinLines(lines, d.trailing), nil
+}
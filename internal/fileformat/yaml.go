@@ -0,0 +1,514 @@
+package fileformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultYAMLIndent = 2
+
+// yamlDoc exposes the scalar leaves of nested mappings as dotted keys. Sequences are left untouched.
+//
+// Render does not re-encode the tree: replaced values are spliced into the original text at
+// the position the parser reported for them, and new keys are inserted after the last line of
+// their mapping, so comments, blank lines, indentation and quoting elsewhere stay as written.
+type yamlDoc struct {
+	src        string
+	lineStarts []int
+	root       *yaml.Node
+	indent     int                     // indentation step used for new nested mappings
+	spans      map[*yaml.Node]yamlSpan // original position of every replaced scalar
+}
+
+// yamlSpan is the source text of a scalar value, without its anchor.
+type yamlSpan struct {
+	start, end int
+	style      yaml.Style
+	flow       bool // the scalar is inside a flow collection
+}
+
+// yamlEdit replaces src[start:end] with text.
+type yamlEdit struct {
+	start, end int
+	text       string
+}
+
+func parseYAML(text string) (*yamlDoc, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &root); err != nil {
+		return nil, err
+	}
+	if root.Kind == 0 {
+		root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if len(root.Content) != 1 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("YAML document must be a mapping")
+	}
+
+	d := &yamlDoc{src: text, lineStarts: []int{0}, root: &root, indent: defaultYAMLIndent, spans: map[*yaml.Node]yamlSpan{}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+	if step := nestedIndent(root.Content[0]); step > 0 {
+		d.indent = step
+	}
+	return d, nil
+}
+
+// nestedIndent returns the indentation of the first block mapping nested in m relative to m,
+// or 0 if there is none.
+func nestedIndent(m *yaml.Node) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		value := m.Content[i+1]
+		if value.Kind != yaml.MappingNode || value.Style&yaml.FlowStyle != 0 {
+			continue
+		}
+		if value.Column > m.Column {
+			return value.Column - m.Column
+		}
+		if step := nestedIndent(value); step > 0 {
+			return step
+		}
+	}
+	return 0
+}
+
+func (d *yamlDoc) Keys() []string {
+	var keys []string
+	var walk func(prefix string, m *yaml.Node)
+	walk = func(prefix string, m *yaml.Node) {
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			key, value := prefix+m.Content[i].Value, m.Content[i+1]
+			switch value.Kind {
+			case yaml.ScalarNode:
+				keys = append(keys, key)
+			case yaml.MappingNode:
+				walk(key+".", value)
+			}
+		}
+	}
+	walk("", d.root.Content[0])
+	return keys
+}
+
+// resolve finds the value node for a dotted key. A mapping key may itself contain dots, as in
+// "spring.datasource.password: x", so a key matching the whole remainder is taken first and
+// nested mappings are tried after it. When the key is not found, node is the deepest mapping
+// reached and rest the part of the key left to create under it.
+func resolve(m *yaml.Node, key string) (parent, node *yaml.Node, rest string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m, m.Content[i+1], ""
+		}
+	}
+	node, rest = m, key
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		k, value := m.Content[i].Value, m.Content[i+1]
+		if value.Kind != yaml.MappingNode || !strings.HasPrefix(key, k+".") {
+			continue
+		}
+		p, n, r := resolve(value, key[len(k)+1:])
+		if r == "" {
+			return p, n, ""
+		}
+		if len(r) < len(rest) {
+			node, rest = n, r
+		}
+	}
+	return nil, node, rest
+}
+
+func (d *yamlDoc) Get(key string) (string, bool) {
+	_, node, rest := resolve(d.root.Content[0], key)
+	if rest != "" || node.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	return node.Value, true
+}
+
+func (d *yamlDoc) Set(key, value string) error {
+	parent, node, rest := resolve(d.root.Content[0], key)
+	if rest != "" {
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if k := node.Content[j].Value; strings.HasPrefix(rest, k+".") {
+				return fmt.Errorf("cannot set %s: %s is not a mapping", key, strings.TrimSuffix(key, rest)+k)
+			}
+		}
+		parts := strings.Split(rest, ".")
+		for i, part := range parts {
+			next := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if i == len(parts)-1 {
+				next = &yaml.Node{Kind: yaml.ScalarNode}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}, next)
+			parent, node = node, next
+		}
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("cannot set %s: not a scalar value", key)
+	}
+	// The span has to be located before the first change, while the node still describes the source
+	if _, ok := d.spans[node]; !ok && node.Line > 0 {
+		d.spans[node] = d.scalarSpan(node, parent.Column-1, parent.Style&yaml.FlowStyle != 0)
+	}
+	node.Tag = "!!str"
+	node.Value = value
+	return nil
+}
+
+func (d *yamlDoc) Render() (string, error) {
+	edits := d.insertions(d.root.Content[0], nil)
+	if n := len(d.src); n > 0 && d.src[n-1] != '\n' {
+		for _, e := range edits {
+			if e.start == n {
+				edits = append(edits, yamlEdit{start: n, end: n, text: "\n"})
+				break
+			}
+		}
+	}
+	for node, span := range d.spans {
+		text := formatYAMLScalar(node.Value, span.style, span.flow)
+		// An empty value sits right after the colon, and may be followed by a comment
+		if span.start == span.end {
+			if span.start > 0 && !isYAMLBlank(d.src[span.start-1]) {
+				text = " " + text
+			}
+			if span.end < len(d.src) && d.src[span.end] == '#' {
+				text += " "
+			}
+		}
+		edits = append(edits, yamlEdit{start: span.start, end: span.end, text: text})
+	}
+
+	// Edits never overlap; applying them back to front keeps the offsets of the others valid.
+	// Of the edits at the same offset the last one applied ends up first: a value before the
+	// keys appended after it, and keys of a nested mapping before those of its parent.
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := d.src
+	for _, e := range edits {
+		out = out[:e.start] + e.text + out[e.end:]
+	}
+	return out, nil
+}
+
+// insertions adds an edit for the keys appended to m by Set, and recurses into the mappings
+// that were already in the source.
+func (d *yamlDoc) insertions(m *yaml.Node, edits []yamlEdit) []yamlEdit {
+	existing := 0
+	for existing+1 < len(m.Content) && m.Content[existing].Line > 0 {
+		existing += 2
+	}
+	added := m.Content[existing:]
+
+	if len(added) > 0 {
+		if m.Style&yaml.FlowStyle != 0 {
+			at, comma := d.flowClose(m)
+			text := formatYAMLFlowPairs(added)
+			if existing > 0 && !comma {
+				text = ", " + text
+			}
+			edits = append(edits, yamlEdit{start: at, end: at, text: text})
+		} else {
+			at, indent := len(d.src), 0
+			if m.Line > 0 {
+				at, indent = d.nodeEnd(m, m.Column-1, false), m.Column-1
+				if nl := strings.IndexByte(d.src[at:], '\n'); nl >= 0 {
+					at += nl + 1
+				} else {
+					at = len(d.src)
+				}
+			}
+			var b strings.Builder
+			d.writeYAMLPairs(&b, added, indent)
+			edits = append(edits, yamlEdit{start: at, end: at, text: b.String()})
+		}
+	}
+
+	for i := 1; i < existing; i += 2 {
+		if m.Content[i].Kind == yaml.MappingNode {
+			edits = d.insertions(m.Content[i], edits)
+		}
+	}
+	return edits
+}
+
+// writeYAMLPairs writes new key/value pairs as a block mapping at the given indentation.
+func (d *yamlDoc) writeYAMLPairs(b *strings.Builder, pairs []*yaml.Node, indent int) {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		key, value := pairs[i], pairs[i+1]
+		b.WriteString(strings.Repeat(" ", indent) + formatYAMLScalar(key.Value, 0, false) + ":")
+		if value.Kind == yaml.MappingNode {
+			b.WriteString("\n")
+			d.writeYAMLPairs(b, value.Content, indent+d.indent)
+			continue
+		}
+		b.WriteString(" " + formatYAMLScalar(value.Value, 0, false) + "\n")
+	}
+}
+
+// formatYAMLFlowPairs writes new key/value pairs as the inside of a flow mapping.
+func formatYAMLFlowPairs(pairs []*yaml.Node) string {
+	var items []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		key, value := pairs[i], pairs[i+1]
+		text := formatYAMLScalar(value.Value, 0, true)
+		if value.Kind == yaml.MappingNode {
+			text = "{" + formatYAMLFlowPairs(value.Content) + "}"
+		}
+		items = append(items, formatYAMLScalar(key.Value, 0, true)+": "+text)
+	}
+	return strings.Join(items, ", ")
+}
+
+// formatYAMLScalar renders value as a single-line string scalar. A quoted original keeps its
+// quote style where the value allows it; otherwise the value stays plain unless it would read
+// back as something else, such as a number, a boolean or a comment.
+func formatYAMLScalar(value string, style yaml.Style, flow bool) string {
+	switch {
+	case style&yaml.SingleQuotedStyle != 0 && singleQuotable(value):
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	case style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) == 0 && plainSafe(value, flow):
+		return value
+	}
+	// A JSON string is a valid double-quoted YAML scalar and never spans lines
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(value)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func singleQuotable(value string) bool {
+	for _, r := range value {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// plainSafe reports whether the encoder would write value unquoted on a single line.
+func plainSafe(value string, flow bool) bool {
+	if value == "" || flow && strings.ContainsAny(value, ",[]{}") {
+		return false
+	}
+	out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+	return err == nil && string(out) == value+"\n"
+}
+
+// offset converts a 1-based line and column, counted in characters, to a byte offset.
+func (d *yamlDoc) offset(line, column int) int {
+	i := d.lineStarts[line-1]
+	for c := 1; c < column && i < len(d.src); c++ {
+		_, size := utf8.DecodeRuneInString(d.src[i:])
+		i += size
+	}
+	return i
+}
+
+// scalarSpan locates the source text of a scalar. indent is the indentation of the
+// collection holding it; continuation lines of the scalar are indented further.
+func (d *yamlDoc) scalarSpan(n *yaml.Node, indent int, flow bool) yamlSpan {
+	src := d.src
+	start := d.offset(n.Line, n.Column)
+	// The anchor stays, so aliases keep pointing at the value
+	if n.Anchor != "" && start < len(src) && src[start] == '&' {
+		start = skipYAMLToken(src, start)
+	}
+	for start < len(src) && isYAMLBlank(src[start]) {
+		start++
+	}
+	v := start
+	if v < len(src) && src[v] == '!' {
+		v = skipYAMLToken(src, v)
+	}
+
+	span := yamlSpan{start: start, style: n.Style, flow: flow}
+	switch {
+	case n.Style&yaml.DoubleQuotedStyle != 0:
+		span.end = len(src)
+		for i := v + 1; i < len(src); i++ {
+			if src[i] == '\\' {
+				i++
+			} else if src[i] == '"' {
+				span.end = i + 1
+				break
+			}
+		}
+	case n.Style&yaml.SingleQuotedStyle != 0:
+		span.end = len(src)
+		for i := v + 1; i < len(src); i++ {
+			if src[i] != '\'' {
+				continue
+			}
+			if i+1 < len(src) && src[i+1] == '\'' {
+				i++
+				continue
+			}
+			span.end = i + 1
+			break
+		}
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		// The header is followed by the lines indented past the collection
+		span.end = v
+		for span.end < len(src) && !isYAMLBlank(src[span.end]) && src[span.end] != '\n' {
+			span.end++
+		}
+		d.eachLineAfter(span.end, func(content, lineEnd, lineIndent int) bool {
+			if lineIndent <= indent {
+				return false
+			}
+			span.end = lineEnd
+			return true
+		})
+	default:
+		var comment bool
+		span.end, comment = plainLineEnd(src, v, flow)
+		// Plain scalars may continue on more indented lines; they fold into spaces
+		if !flow && !comment && strings.ContainsAny(n.Value, " \n") {
+			d.eachLineAfter(span.end, func(content, _, lineIndent int) bool {
+				if lineIndent <= indent || src[content] == '#' {
+					return false
+				}
+				span.end, comment = plainLineEnd(src, content, false)
+				return !comment
+			})
+		}
+	}
+	return span
+}
+
+// eachLineAfter calls fn for each non-blank line following the line containing from, with
+// the offset of its first non-blank character, its end and its indentation, until fn
+// returns false.
+func (d *yamlDoc) eachLineAfter(from int, fn func(content, lineEnd, indent int) bool) {
+	src := d.src
+	for {
+		nl := strings.IndexByte(src[from:], '\n')
+		if nl < 0 {
+			return
+		}
+		lineStart := from + nl + 1
+		lineEnd := len(src)
+		if i := strings.IndexByte(src[lineStart:], '\n'); i >= 0 {
+			lineEnd = lineStart + i
+		}
+		content := lineStart
+		for content < lineEnd && isYAMLBlank(src[content]) {
+			content++
+		}
+		from = lineEnd
+		if content == lineEnd || src[content] == '\r' {
+			continue
+		}
+		trimmed := lineStart + len(strings.TrimRight(src[lineStart:lineEnd], " \t\r"))
+		if !fn(content, trimmed, content-lineStart) {
+			return
+		}
+	}
+}
+
+// nodeEnd returns the offset just past the source text of n, the last scalar of a block
+// collection or the closing bracket of a flow collection.
+func (d *yamlDoc) nodeEnd(n *yaml.Node, indent int, flow bool) int {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if span, ok := d.spans[n]; ok {
+			return span.end
+		}
+		return d.scalarSpan(n, indent, flow).end
+	case yaml.AliasNode:
+		return d.offset(n.Line, n.Column) + len("*"+n.Value)
+	case yaml.MappingNode, yaml.SequenceNode:
+		if n.Style&yaml.FlowStyle != 0 {
+			at, _ := d.flowClose(n)
+			return at + 1
+		}
+		end := d.offset(n.Line, n.Column)
+		for i, child := range n.Content {
+			// A mapping ends with a value, never with a key
+			if n.Kind == yaml.MappingNode && i%2 == 0 || child.Line == 0 {
+				continue
+			}
+			if e := d.nodeEnd(child, n.Column-1, false); e > end {
+				end = e
+			}
+		}
+		return end
+	}
+	return d.offset(n.Line, n.Column)
+}
+
+// flowClose returns the offset of the bracket closing flow collection n, and whether a comma
+// follows its last entry.
+func (d *yamlDoc) flowClose(n *yaml.Node) (int, bool) {
+	src := d.src
+	from := d.offset(n.Line, n.Column) + 1
+	for _, child := range n.Content {
+		if child.Line > 0 {
+			from = max(from, d.nodeEnd(child, n.Column-1, true))
+		}
+	}
+
+	comma := false
+	for i := from; i < len(src); i++ {
+		switch src[i] {
+		case ',':
+			comma = true
+		case '#':
+			if nl := strings.IndexByte(src[i:], '\n'); nl >= 0 {
+				i += nl
+			} else {
+				i = len(src)
+			}
+		case ']', '}':
+			return i, comma
+		}
+	}
+	return len(src), comma
+}
+
+// plainLineEnd returns the end of the plain scalar text on the line starting at from,
+// excluding trailing blanks, and whether a comment follows it.
+func plainLineEnd(src string, from int, flow bool) (int, bool) {
+	end := from
+	for i := from; i < len(src) && src[i] != '\n'; i++ {
+		c := src[i]
+		if c == '#' && (i == from || isYAMLBlank(src[i-1])) {
+			return end, true
+		}
+		if flow && strings.IndexByte(",[]{}", c) >= 0 {
+			break
+		}
+		if !isYAMLBlank(c) && c != '\r' {
+			end = i + 1
+		}
+	}
+	return end, false
+}
+
+// skipYAMLToken skips an anchor or tag starting at i and the blanks after it.
+func skipYAMLToken(src string, i int) int {
+	for i < len(src) && !isYAMLBlank(src[i]) && src[i] != '\n' {
+		i++
+	}
+	for i < len(src) && isYAMLBlank(src[i]) {
+		i++
+	}
+	return i
+}
+
+func isYAMLBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+	"github.com/darthlynx/secret-rotation-lambda/pkg/apitoken"
 	"github.com/sethvargo/go-password/password"
 )
 
@@ -28,6 +29,13 @@ func New() *SecretGenerator {
 
 // Generate creates a new secret based on the provided options
 func (g *SecretGenerator) Generate(opts models.GeneratorOptions) (string, error) {
+	if opts.Format == models.GeneratorFormatAPIToken {
+		return generateAPIToken(opts.Token)
+	}
+	opts, err := ApplyProfile(opts)
+	if err != nil {
+		return "", err
+	}
 	if opts.Length < MinSecretLength {
 		return "", fmt.Errorf("length must be at least %d", MinSecretLength)
 	}
@@ -53,3 +61,14 @@ func (g *SecretGenerator) Generate(opts models.GeneratorOptions) (string, error)
 	}
 	return string(secret), nil
 }
+
+func generateAPIToken(opts *models.TokenOptions) (string, error) {
+	if opts == nil {
+		opts = &models.TokenOptions{}
+	}
+	return apitoken.Generate(apitoken.Options{
+		Prefix:     opts.Prefix,
+		BodyLength: opts.BodyLength,
+		Checksum:   opts.Checksum,
+	})
+}
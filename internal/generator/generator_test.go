@@ -1,11 +1,13 @@
 package generator
 
 import (
+	"strings"
 	"testing"
 
 	"unicode"
 
 	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+	"github.com/darthlynx/secret-rotation-lambda/pkg/apitoken"
 )
 
 func TestGenerate(t *testing.T) {
@@ -186,3 +188,54 @@ func TestGenerateUniqueness(t *testing.T) {
 		secrets[secret] = true
 	}
 }
+
+func TestGenerateAPIToken(t *testing.T) {
+	gen := New()
+	opts := models.GeneratorOptions{
+		Format: models.GeneratorFormatAPIToken,
+		Token: &models.TokenOptions{
+			Prefix:     "acme_live_",
+			BodyLength: 32,
+			Checksum:   apitoken.ChecksumCRC32C,
+		},
+	}
+
+	token, err := gen.Generate(opts)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	err = apitoken.Verify(token, apitoken.Options{Prefix: "acme_live_", BodyLength: 32, Checksum: apitoken.ChecksumCRC32C})
+	if err != nil {
+		t.Errorf("Verify() error for generated token %s: %v", token, err)
+	}
+}
+
+func TestGenerateActiveDirectoryProfile(t *testing.T) {
+	gen := New()
+	secret, err := gen.Generate(models.GeneratorOptions{Profile: models.GeneratorProfileActiveDirectory})
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if len(secret) != ActiveDirectoryMinLength {
+		t.Errorf("length = %d, want %d", len(secret), ActiveDirectoryMinLength)
+	}
+
+	// Complexity: at least three of uppercase, lowercase, digits and special characters
+	classes := 0
+	for _, set := range []string{"ABCDEFGHIJKLMNOPQRSTUVWXYZ", "abcdefghijklmnopqrstuvwxyz", "0123456789"} {
+		if strings.ContainsAny(secret, set) {
+			classes++
+		}
+	}
+	if strings.IndexFunc(secret, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) >= 0 {
+		classes++
+	}
+	if classes < 3 {
+		t.Errorf("%q uses %d character classes, want at least 3", secret, classes)
+	}
+
+	if _, err := gen.Generate(models.GeneratorOptions{Profile: "unknown", Length: 16}); err == nil {
+		t.Error("Generate() accepted an unknown profile")
+	}
+}
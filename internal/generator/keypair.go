@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyPair holds a freshly generated asymmetric key pair and its encodings.
+type KeyPair struct {
+	PrivateKey    crypto.Signer
+	PrivateKeyPEM string // PKCS#8
+	PublicKeyPEM  string // PKIX
+	SSHPublicKey  string // authorized_keys format
+}
+
+// GenerateKeyPair creates a new key pair for the given algorithm.
+func GenerateKeyPair(alg models.KeyAlgorithm) (*KeyPair, error) {
+	key, err := newSigner(alg)
+	if err != nil {
+		return nil, err
+	}
+	return encodeKeyPair(key)
+}
+
+func newSigner(alg models.KeyAlgorithm) (crypto.Signer, error) {
+	switch alg {
+	case models.KeyAlgorithmRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case models.KeyAlgorithmRSA3072:
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case models.KeyAlgorithmRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case models.KeyAlgorithmECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case models.KeyAlgorithmECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case models.KeyAlgorithmEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm: %s", alg)
+	}
+}
+
+func encodeKeyPair(key crypto.Signer) (*KeyPair, error) {
+	privDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	sshKey, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert public key to SSH format: %w", err)
+	}
+
+	return &KeyPair{
+		PrivateKey:    key,
+		PrivateKeyPEM: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})),
+		PublicKeyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})),
+		SSHPublicKey:  strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(sshKey)), "\n"),
+	}, nil
+}
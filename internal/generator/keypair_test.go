@@ -0,0 +1,110 @@
+package generator
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateKeyPair(t *testing.T) {
+	tests := []struct {
+		name      string
+		alg       models.KeyAlgorithm
+		sshPrefix string
+		checkPriv func(key interface{}) bool
+		wantErr   bool
+	}{
+		{
+			name:      "rsa 2048",
+			alg:       models.KeyAlgorithmRSA2048,
+			sshPrefix: "ssh-rsa ",
+			checkPriv: func(key interface{}) bool {
+				k, ok := key.(*rsa.PrivateKey)
+				return ok && k.N.BitLen() == 2048
+			},
+		},
+		{
+			name:      "ecdsa p256",
+			alg:       models.KeyAlgorithmECDSAP256,
+			sshPrefix: "ecdsa-sha2-nistp256 ",
+			checkPriv: func(key interface{}) bool {
+				k, ok := key.(*ecdsa.PrivateKey)
+				return ok && k.Curve.Params().Name == "P-256"
+			},
+		},
+		{
+			name:      "ecdsa p384",
+			alg:       models.KeyAlgorithmECDSAP384,
+			sshPrefix: "ecdsa-sha2-nistp384 ",
+			checkPriv: func(key interface{}) bool {
+				k, ok := key.(*ecdsa.PrivateKey)
+				return ok && k.Curve.Params().Name == "P-384"
+			},
+		},
+		{
+			name:      "ed25519",
+			alg:       models.KeyAlgorithmEd25519,
+			sshPrefix: "ssh-ed25519 ",
+			checkPriv: func(key interface{}) bool {
+				_, ok := key.(ed25519.PrivateKey)
+				return ok
+			},
+		},
+		{
+			name:    "unsupported algorithm",
+			alg:     "dsa-1024",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kp, err := GenerateKeyPair(tt.alg)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("GenerateKeyPair() expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("GenerateKeyPair() unexpected error: %v", err)
+			}
+
+			block, _ := pem.Decode([]byte(kp.PrivateKeyPEM))
+			if block == nil || block.Type != "PRIVATE KEY" {
+				t.Fatalf("GenerateKeyPair() private key is not a PKCS#8 PEM block")
+			}
+			priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				t.Fatalf("Failed to parse private key: %v", err)
+			}
+			if !tt.checkPriv(priv) {
+				t.Errorf("GenerateKeyPair() private key has unexpected type %T", priv)
+			}
+
+			block, _ = pem.Decode([]byte(kp.PublicKeyPEM))
+			if block == nil || block.Type != "PUBLIC KEY" {
+				t.Fatalf("GenerateKeyPair() public key is not a PKIX PEM block")
+			}
+			if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+				t.Errorf("Failed to parse public key: %v", err)
+			}
+
+			if !strings.HasPrefix(kp.SSHPublicKey, tt.sshPrefix) {
+				t.Errorf("SSHPublicKey = %q, want prefix %q", kp.SSHPublicKey, tt.sshPrefix)
+			}
+			if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(kp.SSHPublicKey)); err != nil {
+				t.Errorf("Failed to parse SSH public key: %v", err)
+			}
+		})
+	}
+}
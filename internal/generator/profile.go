@@ -0,0 +1,27 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+)
+
+// ActiveDirectoryMinLength is the minimum length recommended for domain passwords.
+const ActiveDirectoryMinLength = 14
+
+// ApplyProfile returns opts adjusted to the policy named by opts.Profile. Profiles only
+// raise requirements, so a longer length or higher minimum counts are kept.
+func ApplyProfile(opts models.GeneratorOptions) (models.GeneratorOptions, error) {
+	switch opts.Profile {
+	case "":
+	case models.GeneratorProfileActiveDirectory:
+		// Complexity requires three of four character classes. Lowercase letters, digits and
+		// special characters are always present, so uppercase letters are left as requested.
+		opts.Length = max(opts.Length, ActiveDirectoryMinLength)
+		opts.IncludeDigits = true
+		opts.IncludeSpecialChars = true
+	default:
+		return opts, fmt.Errorf("unknown profile %q", opts.Profile)
+	}
+	return opts, nil
+}
@@ -0,0 +1,158 @@
+package hasher
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported verifier formats.
+const (
+	AlgBcrypt         = "bcrypt"
+	AlgArgon2id       = "argon2id"
+	AlgSCRAMSHA256    = "scram-sha-256"   // PostgreSQL pg_authid format
+	AlgHtpasswd       = "htpasswd"        // "user:$2y$..." line
+	AlgRabbitMQSHA256 = "rabbitmq-sha256" // rabbit_password_hashing_sha256
+)
+
+const (
+	BcryptCost = 12
+	// BcryptMaxPasswordLength is the longest password in bytes bcrypt accepts.
+	BcryptMaxPasswordLength = 72
+
+	// Argon2id parameters from the second recommended option of RFC 9106
+	Argon2Time    = 3
+	Argon2Memory  = 64 * 1024
+	Argon2Threads = 4
+	Argon2KeyLen  = 32
+
+	// SCRAMIterations matches PostgreSQL's default scram_iterations
+	SCRAMIterations = 4096
+)
+
+// MaxPasswordLength returns the longest password in bytes the algorithm accepts, or 0 when
+// there is no limit.
+func MaxPasswordLength(alg string) int {
+	switch alg {
+	case AlgBcrypt, AlgHtpasswd:
+		return BcryptMaxPasswordLength
+	default:
+		return 0
+	}
+}
+
+// IsSupported reports whether the algorithm is known.
+func IsSupported(alg string) bool {
+	switch alg {
+	case AlgBcrypt, AlgArgon2id, AlgSCRAMSHA256, AlgHtpasswd, AlgRabbitMQSHA256:
+		return true
+	default:
+		return false
+	}
+}
+
+// NeedsUsername reports whether the algorithm embeds the user name.
+func NeedsUsername(alg string) bool {
+	return alg == AlgHtpasswd
+}
+
+// Hash computes the verifier for password. username is only used by formats that embed it.
+func Hash(alg, password, username string) (string, error) {
+	switch alg {
+	case AlgBcrypt:
+		return bcryptHash(password)
+	case AlgArgon2id:
+		return Argon2id(password)
+	case AlgSCRAMSHA256:
+		return SCRAMSHA256(password)
+	case AlgHtpasswd:
+		if username == "" || strings.Contains(username, ":") {
+			return "", fmt.Errorf("invalid htpasswd user name %q", username)
+		}
+		hash, err := bcryptHash(password)
+		if err != nil {
+			return "", err
+		}
+		// Apache expects the $2y$ prefix, which is the same algorithm as $2a$
+		return username + ":$2y$" + strings.TrimPrefix(hash, "$2a$"), nil
+	case AlgRabbitMQSHA256:
+		return rabbitMQSHA256(password)
+	default:
+		return "", fmt.Errorf("unsupported hash algorithm: %s", alg)
+	}
+}
+
+func bcryptHash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Argon2id returns the PHC string for password.
+func Argon2id(password string) (string, error) {
+	salt, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, Argon2Time, Argon2Memory, Argon2Threads, Argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, Argon2Memory, Argon2Time, Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// SCRAMSHA256 returns a PostgreSQL SCRAM-SHA-256 verifier usable in ALTER ROLE ... PASSWORD.
+func SCRAMSHA256(password string) (string, error) {
+	salt, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	storedKey, serverKey, err := scramKeys(password, salt, SCRAMIterations)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("SCRAM-SHA-256$%d:%s$%s:%s", SCRAMIterations,
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(storedKey),
+		base64.StdEncoding.EncodeToString(serverKey)), nil
+}
+
+func scramKeys(password string, salt []byte, iterations int) (storedKey, serverKey []byte, err error) {
+	salted, err := pbkdf2.Key(sha256.New, password, salt, iterations, sha256.Size)
+	if err != nil {
+		return nil, nil, err
+	}
+	clientKey := hmacSHA256(salted, "Client Key")
+	stored := sha256.Sum256(clientKey)
+	return stored[:], hmacSHA256(salted, "Server Key"), nil
+}
+
+func rabbitMQSHA256(password string) (string, error) {
+	salt, err := randomBytes(4)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append(append([]byte{}, salt...), password...))
+	return base64.StdEncoding.EncodeToString(append(salt, sum[:]...)), nil
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,122 @@
+package hasher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "Sup3r-s3cret!"
+
+func TestHash_Bcrypt(t *testing.T) {
+	hash, err := Hash(AlgBcrypt, testPassword, "")
+	if err != nil {
+		t.Fatalf("Hash() error: %v", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(testPassword)); err != nil {
+		t.Errorf("bcrypt hash does not match password: %v", err)
+	}
+}
+
+func TestHash_Htpasswd(t *testing.T) {
+	line, err := Hash(AlgHtpasswd, testPassword, "proxy")
+	if err != nil {
+		t.Fatalf("Hash() error: %v", err)
+	}
+
+	user, hash, ok := strings.Cut(line, ":")
+	if !ok || user != "proxy" {
+		t.Fatalf("htpasswd line = %q, want proxy:<hash>", line)
+	}
+	if !strings.HasPrefix(hash, "$2y$") {
+		t.Errorf("htpasswd hash = %q, want $2y$ prefix", hash)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(testPassword)); err != nil {
+		t.Errorf("htpasswd hash does not match password: %v", err)
+	}
+
+	if _, err := Hash(AlgHtpasswd, testPassword, ""); err == nil {
+		t.Errorf("Hash() expected error without user name, got nil")
+	}
+}
+
+func TestHash_Argon2id(t *testing.T) {
+	phc, err := Hash(AlgArgon2id, testPassword, "")
+	if err != nil {
+		t.Fatalf("Hash() error: %v", err)
+	}
+
+	var version, memory, iterations, threads int
+	var saltB64, keyB64 string
+	parts := strings.Split(phc, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		t.Fatalf("unexpected PHC string %q", phc)
+	}
+	fmt.Sscanf(parts[2], "v=%d", &version)
+	fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads)
+	saltB64, keyB64 = parts[4], parts[5]
+
+	salt, _ := base64.RawStdEncoding.DecodeString(saltB64)
+	key, _ := base64.RawStdEncoding.DecodeString(keyB64)
+	want := argon2.IDKey([]byte(testPassword), salt, uint32(iterations), uint32(memory), uint8(threads), uint32(len(key)))
+	if version != argon2.Version || !bytes.Equal(key, want) {
+		t.Errorf("argon2id hash does not match password")
+	}
+}
+
+func TestHash_SCRAMSHA256(t *testing.T) {
+	verifier, err := Hash(AlgSCRAMSHA256, testPassword, "")
+	if err != nil {
+		t.Fatalf("Hash() error: %v", err)
+	}
+
+	var iterations int
+	var saltB64, keysPart string
+	head, rest, _ := strings.Cut(verifier, "$")
+	if head != "SCRAM-SHA-256" {
+		t.Fatalf("unexpected verifier %q", verifier)
+	}
+	params, keysPart, _ := strings.Cut(rest, "$")
+	fmt.Sscanf(params, "%d:", &iterations)
+	_, saltB64, _ = strings.Cut(params, ":")
+	storedB64, serverB64, _ := strings.Cut(keysPart, ":")
+
+	salt, _ := base64.StdEncoding.DecodeString(saltB64)
+	stored, server, err := scramKeys(testPassword, salt, iterations)
+	if err != nil {
+		t.Fatalf("scramKeys() error: %v", err)
+	}
+	if iterations != SCRAMIterations ||
+		base64.StdEncoding.EncodeToString(stored) != storedB64 ||
+		base64.StdEncoding.EncodeToString(server) != serverB64 {
+		t.Errorf("SCRAM verifier does not match password")
+	}
+}
+
+func TestHash_RabbitMQSHA256(t *testing.T) {
+	hash, err := Hash(AlgRabbitMQSHA256, testPassword, "")
+	if err != nil {
+		t.Fatalf("Hash() error: %v", err)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil || len(raw) != 4+sha256.Size {
+		t.Fatalf("unexpected RabbitMQ hash %q", hash)
+	}
+	sum := sha256.Sum256(append(append([]byte{}, raw[:4]...), testPassword...))
+	if !bytes.Equal(raw[4:], sum[:]) {
+		t.Errorf("RabbitMQ hash does not match password")
+	}
+}
+
+func TestHash_Unsupported(t *testing.T) {
+	if _, err := Hash("md5", testPassword, ""); err == nil {
+		t.Errorf("Hash() expected error, got nil")
+	}
+}
@@ -0,0 +1,140 @@
+package iam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// Keys of an iam-access-key secret written by the rotator.
+const (
+	FieldAccessKeyID     = "access_key_id"
+	FieldSecretAccessKey = "secret_access_key"
+	FieldSMTPPassword    = "smtp_password"
+)
+
+const (
+	// New keys are usually accepted within a few seconds, but IAM is eventually consistent
+	verifyTimeout  = 30 * time.Second
+	verifyInterval = 2 * time.Second
+)
+
+// AccessKey is an access key of an IAM user. Secret is only known for a key that was just created.
+type AccessKey struct {
+	ID      string
+	Secret  string
+	Active  bool
+	Created time.Time
+}
+
+// Client defines the IAM operations used to rotate access keys.
+type Client interface {
+	ListAccessKeys(ctx context.Context, userName string) ([]AccessKey, error)
+	CreateAccessKey(ctx context.Context, userName string) (AccessKey, error)
+	DeactivateAccessKey(ctx context.Context, userName, keyID string) error
+	DeleteAccessKey(ctx context.Context, userName, keyID string) error
+	// VerifyAccessKey signs a request with the key and returns the ARN of its owner.
+	VerifyAccessKey(ctx context.Context, keyID, secret string) (string, error)
+}
+
+// IAMClient implements the Client interface.
+type IAMClient struct {
+	client *iam.Client
+	cfg    aws.Config
+}
+
+// NewClient creates a new IAMClient.
+func NewClient(cfg aws.Config) *IAMClient {
+	return &IAMClient{
+		client: iam.NewFromConfig(cfg),
+		cfg:    cfg,
+	}
+}
+
+// ListAccessKeys returns the access keys of the user, without their secrets.
+func (c *IAMClient) ListAccessKeys(ctx context.Context, userName string) ([]AccessKey, error) {
+	// A user has at most two keys, so a single page always holds all of them
+	result, err := c.client.ListAccessKeys(ctx, &iam.ListAccessKeysInput{UserName: aws.String(userName)})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]AccessKey, 0, len(result.AccessKeyMetadata))
+	for _, k := range result.AccessKeyMetadata {
+		keys = append(keys, AccessKey{
+			ID:      aws.ToString(k.AccessKeyId),
+			Active:  k.Status == types.StatusTypeActive,
+			Created: aws.ToTime(k.CreateDate),
+		})
+	}
+	return keys, nil
+}
+
+// CreateAccessKey creates an active access key for the user.
+func (c *IAMClient) CreateAccessKey(ctx context.Context, userName string) (AccessKey, error) {
+	result, err := c.client.CreateAccessKey(ctx, &iam.CreateAccessKeyInput{UserName: aws.String(userName)})
+	if err != nil {
+		return AccessKey{}, err
+	}
+
+	return AccessKey{
+		ID:      aws.ToString(result.AccessKey.AccessKeyId),
+		Secret:  aws.ToString(result.AccessKey.SecretAccessKey),
+		Active:  result.AccessKey.Status == types.StatusTypeActive,
+		Created: aws.ToTime(result.AccessKey.CreateDate),
+	}, nil
+}
+
+// DeactivateAccessKey marks the key inactive. It can be activated again until it is deleted.
+func (c *IAMClient) DeactivateAccessKey(ctx context.Context, userName, keyID string) error {
+	_, err := c.client.UpdateAccessKey(ctx, &iam.UpdateAccessKeyInput{
+		UserName:    aws.String(userName),
+		AccessKeyId: aws.String(keyID),
+		Status:      types.StatusTypeInactive,
+	})
+	return err
+}
+
+// DeleteAccessKey deletes the key.
+func (c *IAMClient) DeleteAccessKey(ctx context.Context, userName, keyID string) error {
+	_, err := c.client.DeleteAccessKey(ctx, &iam.DeleteAccessKeyInput{
+		UserName:    aws.String(userName),
+		AccessKeyId: aws.String(keyID),
+	})
+	return err
+}
+
+// VerifyAccessKey calls STS GetCallerIdentity with the key. A key that STS does not know yet
+// is retried until verifyTimeout, since new keys take a moment to propagate.
+func (c *IAMClient) VerifyAccessKey(ctx context.Context, keyID, secret string) (string, error) {
+	client := sts.NewFromConfig(c.cfg, func(o *sts.Options) {
+		o.Credentials = credentials.NewStaticCredentialsProvider(keyID, secret, "")
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+	for {
+		result, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err == nil {
+			return aws.ToString(result.Arn), nil
+		}
+		var apiErr smithy.APIError
+		if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "InvalidClientTokenId" {
+			return "", err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("access key was not accepted within %s: %w", verifyTimeout, err)
+		case <-time.After(verifyInterval):
+		}
+	}
+}
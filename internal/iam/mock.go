@@ -0,0 +1,55 @@
+package iam
+
+import (
+	"context"
+	"errors"
+)
+
+// MockClient is a mock implementation of the Client interface for testing.
+type MockClient struct {
+	ListAccessKeysFunc      func(ctx context.Context, userName string) ([]AccessKey, error)
+	CreateAccessKeyFunc     func(ctx context.Context, userName string) (AccessKey, error)
+	DeactivateAccessKeyFunc func(ctx context.Context, userName, keyID string) error
+	DeleteAccessKeyFunc     func(ctx context.Context, userName, keyID string) error
+	VerifyAccessKeyFunc     func(ctx context.Context, keyID, secret string) (string, error)
+}
+
+// ListAccessKeys calls the mock function.
+func (m *MockClient) ListAccessKeys(ctx context.Context, userName string) ([]AccessKey, error) {
+	if m.ListAccessKeysFunc != nil {
+		return m.ListAccessKeysFunc(ctx, userName)
+	}
+	return nil, errors.New("ListAccessKeysFunc not implemented")
+}
+
+// CreateAccessKey calls the mock function.
+func (m *MockClient) CreateAccessKey(ctx context.Context, userName string) (AccessKey, error) {
+	if m.CreateAccessKeyFunc != nil {
+		return m.CreateAccessKeyFunc(ctx, userName)
+	}
+	return AccessKey{}, errors.New("CreateAccessKeyFunc not implemented")
+}
+
+// DeactivateAccessKey calls the mock function.
+func (m *MockClient) DeactivateAccessKey(ctx context.Context, userName, keyID string) error {
+	if m.DeactivateAccessKeyFunc != nil {
+		return m.DeactivateAccessKeyFunc(ctx, userName, keyID)
+	}
+	return errors.New("DeactivateAccessKeyFunc not implemented")
+}
+
+// DeleteAccessKey calls the mock function.
+func (m *MockClient) DeleteAccessKey(ctx context.Context, userName, keyID string) error {
+	if m.DeleteAccessKeyFunc != nil {
+		return m.DeleteAccessKeyFunc(ctx, userName, keyID)
+	}
+	return errors.New("DeleteAccessKeyFunc not implemented")
+}
+
+// VerifyAccessKey calls the mock function.
+func (m *MockClient) VerifyAccessKey(ctx context.Context, keyID, secret string) (string, error) {
+	if m.VerifyAccessKeyFunc != nil {
+		return m.VerifyAccessKeyFunc(ctx, keyID, secret)
+	}
+	return "", errors.New("VerifyAccessKeyFunc not implemented")
+}
@@ -0,0 +1,22 @@
+package iam
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// smtpPasswordVersion is the version byte prepended to SES SMTP passwords derived with SigV4.
+const smtpPasswordVersion = 0x04
+
+// SMTPPassword derives the Amazon SES SMTP password of an access key for region, using the
+// SigV4 based algorithm from the SES documentation. The access key ID is the SMTP user name.
+func SMTPPassword(secretAccessKey, region string) string {
+	signature := []byte("AWS4" + secretAccessKey)
+	for _, msg := range []string{"11111111", region, "ses", "aws4_request", "SendRawEmail"} {
+		mac := hmac.New(sha256.New, signature)
+		mac.Write([]byte(msg))
+		signature = mac.Sum(nil)
+	}
+	return base64.StdEncoding.EncodeToString(append([]byte{smtpPasswordVersion}, signature...))
+}
@@ -0,0 +1,25 @@
+package iam
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestSMTPPassword(t *testing.T) {
+	const secret = "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"
+
+	if got, want := SMTPPassword(secret, "eu-west-1"), "BMW5RDrXmmVs0lV7GpI4oLkHXpZ4stDsk6q91z1g38Pk"; got != want {
+		t.Errorf("SMTPPassword() = %s, want %s", got, want)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(SMTPPassword(secret, "us-east-1"))
+	if err != nil {
+		t.Fatalf("password is not base64: %v", err)
+	}
+	if len(raw) != 33 || raw[0] != smtpPasswordVersion {
+		t.Errorf("decoded password = %x, want version byte and a 32 byte signature", raw)
+	}
+	if SMTPPassword(secret, "us-east-1") == SMTPPassword(secret, "eu-west-1") {
+		t.Error("password does not depend on the region")
+	}
+}
@@ -0,0 +1,108 @@
+package keyring
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// KeyStatus describes what a key in the ring may still be used for.
+type KeyStatus string
+
+const (
+	StatusPrimary     KeyStatus = "primary"      // encrypts/signs new data
+	StatusDecryptOnly KeyStatus = "decrypt_only" // only decrypts/verifies existing data
+	StatusRetired     KeyStatus = "retired"      // kept for one more rotation, then removed
+)
+
+// Supported key algorithms.
+const (
+	AlgAES256GCM  = "aes-256-gcm"
+	AlgHMACSHA256 = "hmac-sha256"
+	AlgHMACSHA512 = "hmac-sha512"
+)
+
+var keySizes = map[string]int{
+	AlgAES256GCM:  32,
+	AlgHMACSHA256: 32,
+	AlgHMACSHA512: 64,
+}
+
+// Key is a single symmetric key of the ring.
+type Key struct {
+	ID        string    `json:"id"`
+	KeyB64    string    `json:"key_b64"`
+	Status    KeyStatus `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsSupportedAlgorithm reports whether keys can be generated for the algorithm.
+func IsSupportedAlgorithm(alg string) bool {
+	_, ok := keySizes[alg]
+	return ok
+}
+
+// Parse decodes a key ring stored as a JSON array. An empty document is an empty ring.
+func Parse(data string) ([]Key, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var keys []Key
+	if err := json.Unmarshal([]byte(data), &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Rotate adds a new primary key and demotes the previous primary to decrypt-only.
+// Decrypt-only keys beyond retain are retired, and keys that were already retired are removed.
+// Keys are returned newest first.
+func Rotate(keys []Key, alg string, retain int, now time.Time) ([]Key, error) {
+	size, ok := keySizes[alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported key algorithm: %s", alg)
+	}
+
+	material := make([]byte, size)
+	if _, err := rand.Read(material); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate key id: %w", err)
+	}
+
+	result := []Key{{
+		ID:        hex.EncodeToString(id),
+		KeyB64:    base64.StdEncoding.EncodeToString(material),
+		Status:    StatusPrimary,
+		CreatedAt: now.UTC(),
+	}}
+
+	existing := append([]Key(nil), keys...)
+	sort.SliceStable(existing, func(i, j int) bool { return existing[i].CreatedAt.After(existing[j].CreatedAt) })
+
+	decryptOnly := 0
+	for _, k := range existing {
+		switch k.Status {
+		case StatusRetired:
+			continue
+		case StatusPrimary, StatusDecryptOnly:
+			if decryptOnly < retain {
+				k.Status = StatusDecryptOnly
+				decryptOnly++
+			} else {
+				k.Status = StatusRetired
+			}
+		default:
+			return nil, fmt.Errorf("key %s has unknown status %q", k.ID, k.Status)
+		}
+		result = append(result, k)
+	}
+
+	return result, nil
+}
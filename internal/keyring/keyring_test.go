@@ -0,0 +1,89 @@
+package keyring
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestRotate(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var keys []Key
+	var err error
+	for i := 0; i < 4; i++ {
+		keys, err = Rotate(keys, AlgAES256GCM, 2, start.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatalf("Rotate() error: %v", err)
+		}
+	}
+
+	wantStatuses := []KeyStatus{StatusPrimary, StatusDecryptOnly, StatusDecryptOnly, StatusRetired}
+	if len(keys) != len(wantStatuses) {
+		t.Fatalf("len(keys) = %d, want %d", len(keys), len(wantStatuses))
+	}
+	for i, want := range wantStatuses {
+		if keys[i].Status != want {
+			t.Errorf("keys[%d].Status = %s, want %s", i, keys[i].Status, want)
+		}
+	}
+
+	// The retired key is dropped on the next rotation
+	retiredID := keys[3].ID
+	keys, err = Rotate(keys, AlgAES256GCM, 2, start.Add(5*time.Hour))
+	if err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+	for _, k := range keys {
+		if k.ID == retiredID {
+			t.Errorf("retired key %s should have been removed", retiredID)
+		}
+	}
+	if len(keys) != 4 {
+		t.Errorf("len(keys) = %d, want 4", len(keys))
+	}
+}
+
+func TestRotate_KeySizes(t *testing.T) {
+	tests := []struct {
+		alg      string
+		wantSize int
+		wantErr  bool
+	}{
+		{alg: AlgAES256GCM, wantSize: 32},
+		{alg: AlgHMACSHA256, wantSize: 32},
+		{alg: AlgHMACSHA512, wantSize: 64},
+		{alg: "des", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			keys, err := Rotate(nil, tt.alg, 1, time.Now())
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Rotate() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Rotate() error: %v", err)
+			}
+
+			material, err := base64.StdEncoding.DecodeString(keys[0].KeyB64)
+			if err != nil {
+				t.Fatalf("key_b64 is not valid base64: %v", err)
+			}
+			if len(material) != tt.wantSize {
+				t.Errorf("key size = %d, want %d", len(material), tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestRotate_UnknownStatus(t *testing.T) {
+	keys := []Key{{ID: "abc", Status: "active"}}
+	if _, err := Rotate(keys, AlgHMACSHA256, 1, time.Now()); err == nil {
+		t.Errorf("Rotate() expected error for unknown status, got nil")
+	}
+}
@@ -1,40 +1,278 @@
 package models
 
+import "encoding/json"
+
 type SecretType string
 
 const (
-	SecretTypePlaintext SecretType = "plaintext"
-	SecretTypeKeyValue  SecretType = "key-value"
-	SecretTypeJSON      SecretType = "json"
+	SecretTypePlaintext   SecretType = "plaintext"
+	SecretTypeKeyValue    SecretType = "key-value"
+	SecretTypeJSON        SecretType = "json"
+	SecretTypeKeyPair     SecretType = "keypair"
+	SecretTypeCertificate SecretType = "certificate"
+	SecretTypeJWKS        SecretType = "jwks"
+	SecretTypeKeyRing     SecretType = "keyring"
+	SecretTypeURI         SecretType = "uri"
+
+	// Access key of an IAM user, created by IAM rather than the generator
+	SecretTypeIAMAccessKey SecretType = "iam-access-key"
+
+	// Whole configuration files stored as plaintext, rotated with the key-value rules
+	SecretTypeDotenv     SecretType = "dotenv"
+	SecretTypeYAML       SecretType = "yaml"
+	SecretTypeINI        SecretType = "ini"
+	SecretTypeProperties SecretType = "properties"
+)
+
+// KeyAlgorithm identifies the algorithm and size of an asymmetric key pair.
+type KeyAlgorithm string
+
+const (
+	KeyAlgorithmRSA2048   KeyAlgorithm = "rsa-2048"
+	KeyAlgorithmRSA3072   KeyAlgorithm = "rsa-3072"
+	KeyAlgorithmRSA4096   KeyAlgorithm = "rsa-4096"
+	KeyAlgorithmECDSAP256 KeyAlgorithm = "ecdsa-p256"
+	KeyAlgorithmECDSAP384 KeyAlgorithm = "ecdsa-p384"
+	KeyAlgorithmEd25519   KeyAlgorithm = "ed25519"
+)
+
+// Action selects what a request does.
+type Action string
+
+const (
+	ActionRotate Action = "rotate"
+	ActionClone  Action = "clone"
 )
 
 // RotationRequest represents the input parameters for secret rotation.
 type RotationRequest struct {
-	SecretARN      string           `json:"secret_arn"`
-	SecretType     SecretType       `json:"secret_type"`
-	GeneratorOpts  GeneratorOptions `json:"generator_options"`
-	KeyValueConfig *KeyValueConfig  `json:"key_value_config,omitempty"`
+	Action          Action             `json:"action,omitempty"` // defaults to rotate
+	SecretARN       string             `json:"secret_arn"`       // for clone, the secret to create
+	SecretType      SecretType         `json:"secret_type"`
+	GeneratorOpts   GeneratorOptions   `json:"generator_options"`
+	KeyValueConfig  *KeyValueConfig    `json:"key_value_config,omitempty"`
+	KeyPairConfig   *KeyPairConfig     `json:"key_pair_config,omitempty"`
+	CertConfig      *CertificateConfig `json:"certificate_config,omitempty"`
+	JWKSConfig      *JWKSConfig        `json:"jwks_config,omitempty"`
+	KeyRingConfig   *KeyRingConfig     `json:"key_ring_config,omitempty"`
+	AccessKeyConfig *AccessKeyConfig   `json:"access_key_config,omitempty"`
+	Policy          *RotationPolicy    `json:"policy,omitempty"`
+	Tagging         *TaggingConfig     `json:"tagging,omitempty"`
+	CreateIfMissing *CreateConfig      `json:"create_if_missing,omitempty"`
+	CloneConfig     *CloneConfig       `json:"clone_config,omitempty"`
+	Schema          *SchemaConfig      `json:"schema,omitempty"`
+	Connector       *ConnectorConfig   `json:"connector,omitempty"`
+	RequestedBy     string             `json:"requested_by,omitempty"` // self-reported by the caller, replaced by the Cognito identity of the invocation if any
+}
+
+// TaggingConfig asks the rotator to record the rotation on the secret after a successful update
+type TaggingConfig struct {
+	Tags        bool   `json:"tags"`                  // last-rotated-at, last-rotated-by, rotation-generator, next-rotation-due
+	Description string `json:"description,omitempty"` // empty leaves the description unchanged
+}
+
+// SecretProperties describes a secret created by the rotator.
+type SecretProperties struct {
+	Name           string            `json:"name,omitempty"` // defaults to the name part of secret_arn
+	KMSKeyID       string            `json:"kms_key_id,omitempty"`
+	Description    string            `json:"description,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	ReplicaRegions []string          `json:"replica_regions,omitempty"`
+}
+
+// CreateConfig creates the secret with freshly generated values when it does not exist yet.
+type CreateConfig struct {
+	SecretProperties
+	Template map[string]interface{} `json:"template,omitempty"` // initial key-value structure, rotated like an existing secret
+}
+
+// CloneConfig copies the structure of an existing secret into a new secret with freshly generated values.
+type CloneConfig struct {
+	SecretProperties
+	SourceSecretARN string            `json:"source_secret_arn"`
+	Substitutions   map[string]string `json:"substitutions,omitempty"` // text replacements applied to copied values, e.g. hostnames
+}
+
+// SchemaConfig links the secret to a JSON Schema checked before and after rotation.
+// Exactly one source must be set.
+type SchemaConfig struct {
+	Inline    json.RawMessage `json:"inline,omitempty"`
+	SecretARN string          `json:"secret_arn,omitempty"` // secret whose value is the schema
+	FromTag   bool            `json:"from_tag,omitempty"`   // schema secret ARN is read from the rotation-schema tag
+}
+
+// ConnectorStrategy selects how a connector rotates database users.
+type ConnectorStrategy string
+
+const (
+	// StrategySingleUser changes the password of the user in the secret.
+	StrategySingleUser ConnectorStrategy = "single-user"
+	// StrategyAlternatingUsers switches between the user and its clone, so the previous
+	// credentials keep working until the next rotation.
+	StrategyAlternatingUsers ConnectorStrategy = "alternating-users"
+	// StrategyEphemeralUsers creates a new user on every rotation and drops the user
+	// from two rotations ago.
+	StrategyEphemeralUsers ConnectorStrategy = "ephemeral-users"
+)
+
+// ConnectorConfig controls rotation of secrets handled by a connector.
+type ConnectorConfig struct {
+	Strategy ConnectorStrategy `json:"strategy,omitempty"` // defaults to single-user
+
+	// Settings of the ephemeral-users strategy
+	TemplateRole   string `json:"template_role,omitempty"`   // role granted to every new user
+	UsernamePrefix string `json:"username_prefix,omitempty"` // generated names are <prefix>_<timestamp>_<random>
+}
+
+// RotationPolicy describes how often the secret is expected to be rotated.
+type RotationPolicy struct {
+	Interval string `json:"interval"` // Go duration, e.g. "720h"
 }
 
+// GeneratorFormat selects what kind of value the generator produces.
+type GeneratorFormat string
+
+const (
+	GeneratorFormatPassword GeneratorFormat = "password" // default
+	GeneratorFormatAPIToken GeneratorFormat = "api-token"
+)
+
+// GeneratorProfile adjusts password options to the policy of a target system.
+type GeneratorProfile string
+
+const (
+	// GeneratorProfileActiveDirectory meets the default domain policy: complexity enabled
+	// and the recommended minimum length of 14.
+	GeneratorProfileActiveDirectory GeneratorProfile = "active-directory"
+)
+
 // GeneratorOptions defines options for secret generation.
 type GeneratorOptions struct {
-	Length              int  `json:"length"`
-	IncludeDigits       bool `json:"include_digits"`
-	IncludeUppercase    bool `json:"include_uppercase"`
-	IncludeSpecialChars bool `json:"include_special_chars"`
-	MinNumberDigits     int  `json:"min_number_digits,omitempty"`
-	MinNumberSpecial    int  `json:"min_number_special,omitempty"`
+	Format              GeneratorFormat  `json:"format,omitempty"`
+	Profile             GeneratorProfile `json:"profile,omitempty"` // password format only
+	Length              int              `json:"length"`
+	IncludeDigits       bool             `json:"include_digits"`
+	IncludeUppercase    bool             `json:"include_uppercase"`
+	IncludeSpecialChars bool             `json:"include_special_chars"`
+	MinNumberDigits     int              `json:"min_number_digits,omitempty"`
+	MinNumberSpecial    int              `json:"min_number_special,omitempty"`
+	Token               *TokenOptions    `json:"token,omitempty"`
+}
+
+// TokenOptions defines the shape of generated API tokens: <prefix><base62 body><checksum>.
+type TokenOptions struct {
+	Prefix     string `json:"prefix"`                // e.g. "acme_live_"
+	BodyLength int    `json:"body_length,omitempty"` // defaults to 30
+	Checksum   string `json:"checksum,omitempty"`    // crc32 (default) or crc32c
 }
 
 // KeyValueConfig speicifies which keys to rotate in the key-value secrets
 type KeyValueConfig struct {
-	KeysToRotate []string `json:"keys_to_rotate"` // empty means rotate all
+	KeysToRotate  []string       `json:"keys_to_rotate"`     // empty means rotate all
+	URIKeys       []string       `json:"uri_keys,omitempty"` // keys holding URIs, only their password is rotated
+	DerivedFields []DerivedField `json:"derived_fields,omitempty"`
+	// Templates maps target keys to text/template expressions evaluated against the rotated secret,
+	// e.g. "DATABASE_URL": "postgres://{{.username}}:{{urlquery .password}}@{{.host}}/{{.dbname}}"
+	Templates map[string]string `json:"templates,omitempty"`
+	Metadata  *MetadataConfig   `json:"metadata,omitempty"`
+}
+
+// MetadataConfig selects bookkeeping fields written into the secret on every rotation
+type MetadataConfig struct {
+	RotatedAt         bool `json:"rotated_at"`         // RFC 3339 time of the rotation
+	CredentialVersion bool `json:"credential_version"` // incremented on every rotation, starting at 1
+	RotatedBy         bool `json:"rotated_by"`         // RotationRequest.RequestedBy, not verified unless invoked through Cognito
+	ExpiresAt         bool `json:"expires_at"`         // rotated_at plus the policy interval
+}
+
+// DerivedField is a verifier recomputed from a rotated key and written in the same secret version
+type DerivedField struct {
+	Key         string `json:"key"`                    // target key, e.g. "password_bcrypt"
+	Source      string `json:"source"`                 // rotated key, e.g. "password"
+	Algorithm   string `json:"algorithm"`              // bcrypt, argon2id, scram-sha-256, htpasswd or rabbitmq-sha256
+	UsernameKey string `json:"username_key,omitempty"` // htpasswd only, defaults to "username"
+}
+
+// KeyPairConfig specifies which key pair to generate and where to store it in the key-value secret
+type KeyPairConfig struct {
+	Algorithm         KeyAlgorithm `json:"algorithm"`
+	PrivateKeyField   string       `json:"private_key_field,omitempty"`    // defaults to "private_key"
+	PublicKeyField    string       `json:"public_key_field,omitempty"`     // defaults to "public_key"
+	SSHPublicKeyField string       `json:"ssh_public_key_field,omitempty"` // empty means not stored
+}
+
+// CertificateConfig specifies the X.509 certificate to issue and the CA secret used to sign it.
+// The CA secret is a key-value secret holding "certificate", "private_key" and optionally "chain" PEM.
+type CertificateConfig struct {
+	CASecretARN  string             `json:"ca_secret_arn"`
+	KeyAlgorithm KeyAlgorithm       `json:"key_algorithm"`
+	Subject      CertificateSubject `json:"subject"`
+	DNSNames     []string           `json:"dns_names,omitempty"`
+	IPAddresses  []string           `json:"ip_addresses,omitempty"`
+	URIs         []string           `json:"uris,omitempty"`
+	Validity     string             `json:"validity"`                 // Go duration, e.g. "2160h"
+	MinValidity  string             `json:"min_validity,omitempty"`   // shortest lifetime accepted when the CA expires sooner; defaults to validity
+	RenewBefore  string             `json:"renew_before,omitempty"`   // empty means always rotate
+	ExtKeyUsages []string           `json:"ext_key_usages,omitempty"` // "server_auth", "client_auth"; defaults to both
+}
+
+// CertificateSubject is the distinguished name of an issued certificate.
+type CertificateSubject struct {
+	CommonName         string   `json:"common_name"`
+	Organization       []string `json:"organization,omitempty"`
+	OrganizationalUnit []string `json:"organizational_unit,omitempty"`
+	Country            []string `json:"country,omitempty"`
+	Province           []string `json:"province,omitempty"`
+	Locality           []string `json:"locality,omitempty"`
+}
+
+// JWKSConfig specifies how the JWT signing key ring is rotated
+type JWKSConfig struct {
+	Algorithm       string `json:"algorithm"`                   // RS256, ES256, ES384 or EdDSA
+	ActivationDelay string `json:"activation_delay,omitempty"`  // Go duration before a new key signs, e.g. "24h"
+	MaxKeys         int    `json:"max_keys,omitempty"`          // generations kept in the ring, defaults to 3
+	PublicSecretARN string `json:"public_secret_arn,omitempty"` // secret that receives the public JWK Set
+}
+
+// KeyRingConfig specifies how the symmetric key ring is rotated
+type KeyRingConfig struct {
+	Algorithm string `json:"algorithm"`        // aes-256-gcm, hmac-sha256 or hmac-sha512
+	Retain    *int   `json:"retain,omitempty"` // decrypt-only keys kept after demotion, defaults to 2, 0 keeps none
+}
+
+// AccessKeyConfig names the IAM user whose access key is stored in the secret
+type AccessKeyConfig struct {
+	UserName   string `json:"user_name"`
+	SMTPRegion string `json:"smtp_region,omitempty"` // also stores the SES SMTP password for this region
+}
+
+// FieldError describes a problem with one field of a request or secret.
+type FieldError struct {
+	Field   string `json:"field"` // dotted path, empty for the document itself
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ConnectorStatus reports the steps a connector ran against the target system.
+// Credentials are redacted from Error. Rotations of iam-access-key secrets report their
+// steps here too: delete_key, create_key, test_secret, promote and deactivate_key.
+type ConnectorStatus struct {
+	Engine    string   `json:"engine"`
+	Completed []string `json:"completed,omitempty"` // clone_user, create_user, set_secret, test_secret, promote, drop_user, finish_secret, rollback
+	Failed    string   `json:"failed,omitempty"`    // step that failed, if any
+	Error     string   `json:"error,omitempty"`
 }
 
 // RotationResponse represents the result of a secret rotation operation.
 type RotationResponse struct {
-	Success   bool   `json:"success"`
-	SecretARN string `json:"secret_arn"`
-	VersionID string `json:"version_id,omitempty"`
-	ErrorMsg  string `json:"error_msg,omitempty"`
+	Success     bool             `json:"success"`
+	SecretARN   string           `json:"secret_arn"`
+	VersionID   string           `json:"version_id,omitempty"`
+	Skipped     bool             `json:"skipped,omitempty"`
+	Created     bool             `json:"created,omitempty"`
+	Message     string           `json:"message,omitempty"`
+	ErrorMsg    string           `json:"error_msg,omitempty"`
+	Warnings    []string         `json:"warnings,omitempty"`     // problems after the secret was already updated
+	FieldErrors []FieldError     `json:"field_errors,omitempty"` // request validation or schema violations behind ErrorMsg
+	Connector   *ConnectorStatus `json:"connector,omitempty"`    // steps run against the target system, set once any step ran
 }
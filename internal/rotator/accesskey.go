@@ -0,0 +1,195 @@
+package rotator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/darthlynx/secret-rotation-lambda/internal/iam"
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+	"github.com/darthlynx/secret-rotation-lambda/internal/schema"
+)
+
+// accessKeyEngine is reported as the engine of iam-access-key rotations.
+const accessKeyEngine = "iam"
+
+// Access key steps reported in models.ConnectorStatus.
+const (
+	stepDeleteKey     = "delete_key"
+	stepCreateKey     = "create_key"
+	stepDeactivateKey = "deactivate_key"
+)
+
+// WithIAMClient enables rotation of iam-access-key secrets.
+func WithIAMClient(c iam.Client) Option {
+	return func(r *Rotator) {
+		r.iamClient = c
+	}
+}
+
+// rotateAccessKey replaces the access key stored in the secret with a new key of the same
+// IAM user. The new key is stored as AWSPENDING and only promoted once it authenticates.
+// The previous key is deactivated rather than deleted, so it can be switched back on if
+// something still uses it; it is deleted by the next rotation.
+func (r *Rotator) rotateAccessKey(ctx context.Context, req models.RotationRequest, sch *schema.Schema) (*models.RotationResponse, error) {
+	status := &models.ConnectorStatus{Engine: accessKeyEngine}
+	versionID, warnings, err := r.applyAccessKey(ctx, req, sch, status)
+	if err != nil {
+		resp := &models.RotationResponse{
+			Success:     false,
+			SecretARN:   req.SecretARN,
+			ErrorMsg:    err.Error(),
+			FieldErrors: fieldErrors(err),
+		}
+		if len(status.Completed) > 0 || status.Failed != "" {
+			resp.Connector = status
+		}
+		return resp, err
+	}
+
+	return &models.RotationResponse{
+		Success:   true,
+		SecretARN: req.SecretARN,
+		VersionID: versionID,
+		Warnings:  append(warnings, r.recordRotation(ctx, req, r.now())...),
+		Connector: status,
+	}, nil
+}
+
+func (r *Rotator) applyAccessKey(ctx context.Context, req models.RotationRequest, sch *schema.Schema,
+	status *models.ConnectorStatus) (string, []string, error) {
+	if r.iamClient == nil {
+		return "", nil, errors.New("iam-access-key secrets are not enabled, no IAM client is configured")
+	}
+	cfg := req.AccessKeyConfig
+
+	secret, err := r.getSecretMap(ctx, req.SecretARN)
+	if err != nil {
+		return "", nil, err
+	}
+	currentID, _ := secret[iam.FieldAccessKeyID].(string)
+
+	var newKey iam.AccessKey
+	run := func(step string, fn func() error) error {
+		if err := fn(); err != nil {
+			status.Failed = step
+			status.Error = connector.Redact(err.Error(), newKey.Secret)
+			return fmt.Errorf("%s failed: %s", step, status.Error)
+		}
+		status.Completed = append(status.Completed, step)
+		return nil
+	}
+
+	if err := r.freeAccessKeySlot(ctx, cfg.UserName, currentID, run); err != nil {
+		return "", nil, err
+	}
+	if err := run(stepCreateKey, func() error {
+		newKey, err = r.iamClient.CreateAccessKey(ctx, cfg.UserName)
+		return err
+	}); err != nil {
+		return "", nil, err
+	}
+
+	// Until the new key is promoted nothing depends on it, so a failure removes it again and
+	// leaves the slot free for the next attempt
+	versionID, err := r.stageAccessKey(ctx, req, sch, secret, newKey, run)
+	if err != nil {
+		if delErr := r.iamClient.DeleteAccessKey(ctx, cfg.UserName, newKey.ID); delErr != nil {
+			return "", nil, fmt.Errorf("%w; failed to delete new access key %s: %v", err, newKey.ID, delErr)
+		}
+		return "", nil, err
+	}
+	// A failed promotion may already have moved AWSCURRENT, so the new key must stay
+	if err := run(stepPromote, func() error { return r.smClient.PromoteVersion(ctx, req.SecretARN, versionID) }); err != nil {
+		return "", nil, err
+	}
+
+	// The new key is live, so a failed cleanup must not fail the rotation
+	var warnings []string
+	if currentID != "" && currentID != newKey.ID {
+		if err := run(stepDeactivateKey, func() error {
+			return r.iamClient.DeactivateAccessKey(ctx, cfg.UserName, currentID)
+		}); err != nil {
+			warnings = append(warnings, err.Error())
+		}
+	}
+	return versionID, warnings, nil
+}
+
+// freeAccessKeySlot deletes the inactive keys left by earlier rotations, so the user has room
+// for a new key under the IAM limit of two. Another active key is not touched: it is either
+// used outside this secret or the new key of an interrupted rotation, and either way deleting
+// it could break a client.
+func (r *Rotator) freeAccessKeySlot(ctx context.Context, userName, currentID string, run func(string, func() error) error) error {
+	keys, err := r.iamClient.ListAccessKeys(ctx, userName)
+	if err != nil {
+		return fmt.Errorf("failed to list access keys of %s: %w", userName, err)
+	}
+
+	var stale []string
+	for _, k := range keys {
+		if k.ID == currentID {
+			continue
+		}
+		if k.Active {
+			return fmt.Errorf("user %s has active access key %s that is not stored in the secret; "+
+				"deactivate or delete it before rotating", userName, k.ID)
+		}
+		stale = append(stale, k.ID)
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	return run(stepDeleteKey, func() error {
+		for _, id := range stale {
+			if err := r.iamClient.DeleteAccessKey(ctx, userName, id); err != nil {
+				return fmt.Errorf("failed to delete access key %s: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// stageAccessKey writes the new key into the secret as AWSPENDING and checks that it
+// authenticates as the user.
+func (r *Rotator) stageAccessKey(ctx context.Context, req models.RotationRequest, sch *schema.Schema,
+	secret map[string]interface{}, key iam.AccessKey, run func(string, func() error) error) (string, error) {
+	cfg := req.AccessKeyConfig
+	secret[iam.FieldAccessKeyID] = key.ID
+	secret[iam.FieldSecretAccessKey] = key.Secret
+	if cfg.SMTPRegion != "" {
+		secret[iam.FieldSMTPPassword] = iam.SMTPPassword(key.Secret, cfg.SMTPRegion)
+	}
+
+	result, err := json.Marshal(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal updated secret: %w", err)
+	}
+	value := string(result)
+	if err := checkNewValue(sch, req.SecretType, value); err != nil {
+		return "", err
+	}
+
+	versionID, err := r.smClient.PutPendingSecretValue(ctx, req.SecretARN, value)
+	if err != nil {
+		return "", fmt.Errorf("failed to store pending secret: %w", err)
+	}
+	if err := run(stepTestSecret, func() error {
+		arn, err := r.iamClient.VerifyAccessKey(ctx, key.ID, key.Secret)
+		if err != nil {
+			return err
+		}
+		// User ARNs end in :user/<path><name>
+		if !strings.Contains(arn, ":user/") || !strings.HasSuffix(arn, "/"+cfg.UserName) {
+			return fmt.Errorf("access key authenticates as %s, not user %s", arn, cfg.UserName)
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	return versionID, nil
+}
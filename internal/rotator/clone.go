@@ -0,0 +1,120 @@
+package rotator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/fileformat"
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+	"github.com/darthlynx/secret-rotation-lambda/internal/schema"
+)
+
+// cloneSecret creates req.SecretARN with the structure of the source secret. Copied values are
+// rewritten through the substitution table and the rotatable keys get freshly generated values.
+func (r *Rotator) cloneSecret(ctx context.Context, req models.RotationRequest, sch *schema.Schema) (*models.RotationResponse, error) {
+	cfg := req.CloneConfig
+
+	value, err := r.cloneValue(ctx, req)
+	if err == nil {
+		err = checkNewValue(sch, req.SecretType, value)
+	}
+	if err != nil {
+		return &models.RotationResponse{
+			Success:     false,
+			SecretARN:   req.SecretARN,
+			ErrorMsg:    err.Error(),
+			FieldErrors: fieldErrors(err),
+		}, err
+	}
+
+	props := cfg.SecretProperties
+	if props.Name == "" {
+		props.Name = secretName(req.SecretARN)
+	}
+	arn, versionID, err := r.smClient.CreateSecret(ctx, props, value)
+	if err != nil {
+		return &models.RotationResponse{
+			Success:   false,
+			SecretARN: req.SecretARN,
+			ErrorMsg:  fmt.Sprintf("failed to create secret: %v", err),
+		}, err
+	}
+	req.SecretARN = arn
+
+	return &models.RotationResponse{
+		Success:   true,
+		SecretARN: arn,
+		VersionID: versionID,
+		Created:   true,
+		Message:   "cloned from " + cfg.SourceSecretARN,
+		Warnings:  r.recordRotation(ctx, req, r.now()),
+	}, nil
+}
+
+func (r *Rotator) cloneValue(ctx context.Context, req models.RotationRequest) (string, error) {
+	source, err := r.smClient.GetSecretValue(ctx, req.CloneConfig.SourceSecretARN)
+	if err != nil {
+		return "", fmt.Errorf("failed to get source secret: %w", err)
+	}
+	replacer := substitutionReplacer(req.CloneConfig.Substitutions)
+
+	switch req.SecretType {
+	case models.SecretTypeKeyValue, models.SecretTypeJSON:
+		secret, err := parseSecretMap(source)
+		if err != nil {
+			return "", err
+		}
+		for key, value := range secret {
+			if s, ok := value.(string); ok {
+				secret[key] = replacer.Replace(s)
+			}
+		}
+		if _, err := r.rotateFields(req, secret); err != nil {
+			return "", err
+		}
+		result, err := json.Marshal(secret)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal cloned secret: %w", err)
+		}
+		return string(result), nil
+	case models.SecretTypeDotenv, models.SecretTypeYAML, models.SecretTypeINI, models.SecretTypeProperties:
+		doc, err := fileformat.Parse(string(req.SecretType), source)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse source secret as %s: %w", req.SecretType, err)
+		}
+		for _, key := range doc.Keys() {
+			value, _ := doc.Get(key)
+			if replaced := replacer.Replace(value); replaced != value {
+				if err := doc.Set(key, replaced); err != nil {
+					return "", fmt.Errorf("failed to update key %s: %w", key, err)
+				}
+			}
+		}
+		return r.rotateDocument(req, doc)
+	default:
+		return "", fmt.Errorf("clone is not supported for secret type: %s", req.SecretType)
+	}
+}
+
+// substitutionReplacer replaces the longest matches first, so "db.prod.internal" wins over "prod".
+func substitutionReplacer(substitutions map[string]string) *strings.Replacer {
+	olds := make([]string, 0, len(substitutions))
+	for old := range substitutions {
+		olds = append(olds, old)
+	}
+	sort.Slice(olds, func(i, j int) bool {
+		if len(olds[i]) != len(olds[j]) {
+			return len(olds[i]) > len(olds[j])
+		}
+		return olds[i] < olds[j]
+	})
+
+	pairs := make([]string, 0, 2*len(olds))
+	for _, old := range olds {
+		pairs = append(pairs, old, substitutions[old])
+	}
+	return strings.NewReplacer(pairs...)
+}
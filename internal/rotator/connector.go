@@ -0,0 +1,206 @@
+package rotator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+)
+
+// Option configures a Rotator.
+type Option func(*Rotator)
+
+// WithConnector registers a connector for key-value secrets whose engine field equals engine.
+func WithConnector(engine string, c connector.Connector) Option {
+	return func(r *Rotator) {
+		r.connectors[engine] = c
+	}
+}
+
+// connectorFor returns the connector registered for the engine of a key-value secret.
+func (r *Rotator) connectorFor(secretType models.SecretType, secret map[string]interface{}) connector.Connector {
+	if secretType != models.SecretTypeKeyValue && secretType != models.SecretTypeJSON {
+		return nil
+	}
+	return r.connectors[connector.Engine(secret)]
+}
+
+// Connector steps reported in models.ConnectorStatus.
+const (
+	stepCloneUser    = "clone_user"
+	stepCreateUser   = "create_user"
+	stepSetSecret    = "set_secret"
+	stepTestSecret   = "test_secret"
+	stepPromote      = "promote"
+	stepDropUser     = "drop_user"
+	stepFinishSecret = "finish_secret"
+	stepRollback     = "rollback"
+)
+
+// rotateWithConnector stores the new value as AWSPENDING, applies it to the target system
+// and only promotes it to AWSCURRENT once the new credentials have been tested.
+func (r *Rotator) rotateWithConnector(ctx context.Context, req models.RotationRequest, conn connector.Connector,
+	engine, pendingValue string) (*models.RotationResponse, error) {
+	status := &models.ConnectorStatus{Engine: engine}
+	versionID, warnings, err := r.applyConnector(ctx, req, conn, status, pendingValue)
+	if err != nil {
+		return &models.RotationResponse{
+			Success:   false,
+			SecretARN: req.SecretARN,
+			ErrorMsg:  err.Error(),
+			Connector: status,
+		}, err
+	}
+
+	return &models.RotationResponse{
+		Success:   true,
+		SecretARN: req.SecretARN,
+		VersionID: versionID,
+		Warnings:  append(warnings, r.recordRotation(ctx, req, r.now())...),
+		Connector: status,
+	}, nil
+}
+
+func (r *Rotator) applyConnector(ctx context.Context, req models.RotationRequest, conn connector.Connector,
+	status *models.ConnectorStatus, pendingValue string) (string, []string, error) {
+	currentMap, err := r.getSecretMap(ctx, req.SecretARN)
+	if err != nil {
+		return "", nil, err
+	}
+	previous, err := connector.ParseCredentials(currentMap)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid current secret: %w", err)
+	}
+	pendingMap, err := parseSecretMap(pendingValue)
+	if err != nil {
+		return "", nil, err
+	}
+	pending, err := connector.ParseCredentials(pendingMap)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid pending secret: %w", err)
+	}
+	admin, err := r.adminCredentials(ctx, pendingMap, previous)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Driver errors can echo statements or connection strings, so only redacted text leaves this function
+	run := func(step string, fn func() error) error {
+		if err := fn(); err != nil {
+			status.Failed = step
+			status.Error = connector.Redact(err.Error(), pending.Password, previous.Password, admin.Password)
+			return fmt.Errorf("%s failed: %s", step, status.Error)
+		}
+		status.Completed = append(status.Completed, step)
+		return nil
+	}
+
+	// prepareStrategy has checked this for the new value, but nothing is staged before it holds
+	cloner, canClone := conn.(connector.UserCloner)
+	manager, canManage := conn.(connector.UserManager)
+	if s := strategy(req); s == models.StrategyAlternatingUsers && !canClone || s == models.StrategyEphemeralUsers && !canManage {
+		return "", nil, fmt.Errorf("engine %s does not support the %s strategy", status.Engine, s)
+	}
+
+	versionID, err := r.smClient.PutPendingSecretValue(ctx, req.SecretARN, pendingValue)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to store pending secret: %w", err)
+	}
+
+	// Once the target has been changed, a failed test or promotion undoes the change, so the
+	// current version keeps working and a retry starts from where this attempt did
+	var undo func() error
+	switch strategy(req) {
+	case models.StrategyAlternatingUsers:
+		// The active user is the template, so the clone gets the same privileges. The active
+		// user is never changed, so there is nothing to undo
+		if err := run(stepCloneUser, func() error { return cloner.CloneUser(ctx, admin, previous.Username, pending) }); err != nil {
+			return "", nil, err
+		}
+		if err := run(stepSetSecret, func() error { return conn.SetSecret(ctx, admin, pending) }); err != nil {
+			return "", nil, err
+		}
+	case models.StrategyEphemeralUsers:
+		// A new user is created with its password, so there is nothing left to set
+		if err := run(stepCreateUser, func() error {
+			return manager.CreateUser(ctx, admin, req.Connector.TemplateRole, pending)
+		}); err != nil {
+			return "", nil, err
+		}
+		undo = func() error { return manager.DropUser(ctx, admin, pending.Username) }
+	default:
+		if err := run(stepSetSecret, func() error { return conn.SetSecret(ctx, admin, pending) }); err != nil {
+			return "", nil, err
+		}
+		undo = func() error { return revertSecret(ctx, conn, admin, pending, previous) }
+	}
+
+	rollback := func(err error) error {
+		if undo == nil {
+			return err
+		}
+		if uerr := undo(); uerr != nil {
+			msg := connector.Redact(uerr.Error(), pending.Password, previous.Password, admin.Password)
+			status.Error += "; rollback failed: " + msg
+			return fmt.Errorf("%w; rollback failed: %s", err, msg)
+		}
+		status.Completed = append(status.Completed, stepRollback)
+		return err
+	}
+	if err := run(stepTestSecret, func() error { return conn.TestSecret(ctx, pending) }); err != nil {
+		return "", nil, rollback(err)
+	}
+	if err := run(stepPromote, func() error { return r.smClient.PromoteVersion(ctx, req.SecretARN, versionID) }); err != nil {
+		return "", nil, rollback(err)
+	}
+
+	// The new version is live, so a failed cleanup must not fail the rotation
+	var warnings []string
+	if strategy(req) == models.StrategyEphemeralUsers {
+		// The current secret still names the user from two rotations ago
+		if stale, _ := currentMap[connector.FieldPreviousUsername].(string); stale != "" && stale != pending.Username {
+			if err := run(stepDropUser, func() error { return manager.DropUser(ctx, admin, stale) }); err != nil {
+				warnings = append(warnings, err.Error())
+			}
+		}
+	}
+	if f, ok := conn.(connector.Finisher); ok {
+		if err := run(stepFinishSecret, func() error { return f.FinishSecret(ctx, admin, pending, previous) }); err != nil {
+			warnings = append(warnings, err.Error())
+		}
+	}
+	return versionID, warnings, nil
+}
+
+// revertSecret undoes SetSecret for a single user. Connectors that keep both passwords drop the
+// pending one; others have the previous password set again, by the user itself when it changed
+// its own password, as the old password no longer logs in.
+func revertSecret(ctx context.Context, conn connector.Connector, admin, pending, previous connector.Credentials) error {
+	if rv, ok := conn.(connector.Reverter); ok {
+		return rv.RevertSecret(ctx, admin, pending, previous)
+	}
+	if admin.Username == pending.Username {
+		admin = pending
+	}
+	return conn.SetSecret(ctx, admin, previous)
+}
+
+// adminCredentials returns the credentials used to change the password: those of the secret
+// referenced by masterarn, or the current credentials of the user itself.
+func (r *Rotator) adminCredentials(ctx context.Context, pending map[string]interface{}, current connector.Credentials) (connector.Credentials, error) {
+	masterARN, _ := pending[connector.FieldMasterARN].(string)
+	if masterARN == "" {
+		return current, nil
+	}
+
+	masterMap, err := r.getSecretMap(ctx, masterARN)
+	if err != nil {
+		return connector.Credentials{}, fmt.Errorf("failed to load master secret: %w", err)
+	}
+	admin, err := connector.ParseCredentials(masterMap)
+	if err != nil {
+		return connector.Credentials{}, fmt.Errorf("invalid master secret: %w", err)
+	}
+	return admin, nil
+}
@@ -0,0 +1,95 @@
+package rotator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/fileformat"
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+	"github.com/darthlynx/secret-rotation-lambda/internal/secretsmanager"
+)
+
+// getExisting fetches the secret being rotated. When it does not exist and create_if_missing
+// is set, the initial value built from the template is returned instead.
+func (r *Rotator) getExisting(ctx context.Context, req models.RotationRequest) (string, error) {
+	existing, err := r.smClient.GetSecretValue(ctx, req.SecretARN)
+	if errors.Is(err, secretsmanager.ErrSecretNotFound) && req.CreateIfMissing != nil {
+		return initialValue(req)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get existing secret: %w", err)
+	}
+	return existing, nil
+}
+
+// getExistingMap is getExisting for secrets stored as a JSON object.
+func (r *Rotator) getExistingMap(ctx context.Context, req models.RotationRequest) (map[string]interface{}, error) {
+	existing, err := r.getExisting(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return parseSecretMap(existing)
+}
+
+// initialValue renders the create_if_missing template in the format of the secret type.
+// Plaintext, JWKS and key ring secrets start out empty.
+func initialValue(req models.RotationRequest) (string, error) {
+	template := req.CreateIfMissing.Template
+
+	switch req.SecretType {
+	case models.SecretTypeKeyValue, models.SecretTypeJSON, models.SecretTypeKeyPair, models.SecretTypeCertificate:
+		if template == nil {
+			template = map[string]interface{}{}
+		}
+		result, err := json.Marshal(template)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal create_if_missing template: %w", err)
+		}
+		return string(result), nil
+	case models.SecretTypeDotenv, models.SecretTypeYAML, models.SecretTypeINI, models.SecretTypeProperties:
+		doc, err := fileformat.Parse(string(req.SecretType), "")
+		if err != nil {
+			return "", err
+		}
+		keys := make([]string, 0, len(template))
+		for key := range template {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := doc.Set(key, fmt.Sprint(template[key])); err != nil {
+				return "", fmt.Errorf("failed to set template key %s: %w", key, err)
+			}
+		}
+		return doc.Render()
+	default:
+		return "", nil
+	}
+}
+
+// createSecret creates the secret with its first value and returns the new ARN and version ID.
+func (r *Rotator) createSecret(ctx context.Context, req models.RotationRequest, value string) (string, string, error) {
+	props := req.CreateIfMissing.SecretProperties
+	if props.Name == "" {
+		props.Name = secretName(req.SecretARN)
+	}
+	return r.smClient.CreateSecret(ctx, props, value)
+}
+
+// arnSuffix matches the six random characters Secrets Manager appends to the name in a full ARN.
+var arnSuffix = regexp.MustCompile(`-[A-Za-z0-9]{6}$`)
+
+// secretName returns the name part of a secret ARN without the random suffix of a full ARN,
+// or the input itself when it is not an ARN. A partial ARN whose name ends in a hyphen and six
+// characters looks like a full one, AWS advises against such names for the same reason.
+func secretName(secretARN string) string {
+	if i := strings.Index(secretARN, ":secret:"); strings.HasPrefix(secretARN, "arn:") && i >= 0 {
+		return arnSuffix.ReplaceAllString(secretARN[i+len(":secret:"):], "")
+	}
+	return secretARN
+}
@@ -0,0 +1,25 @@
+package rotator
+
+import "testing"
+
+func TestSecretName(t *testing.T) {
+	tests := []struct {
+		name string
+		arn  string
+		want string
+	}{
+		{name: "full ARN", arn: "arn:aws:secretsmanager:us-east-1:123456789012:secret:prod/db-AbCdEf", want: "prod/db"},
+		{name: "partial ARN", arn: "arn:aws:secretsmanager:us-east-1:123456789012:secret:prod/db", want: "prod/db"},
+		{name: "hyphenated name", arn: "arn:aws:secretsmanager:us-east-1:123456789012:secret:prod/orders-db-XyZ123", want: "prod/orders-db"},
+		{name: "plain name", arn: "prod/db", want: "prod/db"},
+		{name: "plain name with suffix-like ending", arn: "prod/db-AbCdEf", want: "prod/db-AbCdEf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := secretName(tt.arn); got != tt.want {
+				t.Errorf("secretName(%q) = %q, want %q", tt.arn, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package rotator
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+)
+
+// Keys written by MetadataConfig.
+const (
+	metadataRotatedAt         = "rotated_at"
+	metadataCredentialVersion = "credential_version"
+	metadataRotatedBy         = "rotated_by"
+	metadataExpiresAt         = "expires_at"
+)
+
+// metadataKeys returns the bookkeeping keys enabled by cfg.
+func metadataKeys(cfg *models.MetadataConfig) []string {
+	if cfg == nil {
+		return nil
+	}
+	var keys []string
+	if cfg.RotatedAt {
+		keys = append(keys, metadataRotatedAt)
+	}
+	if cfg.CredentialVersion {
+		keys = append(keys, metadataCredentialVersion)
+	}
+	if cfg.RotatedBy {
+		keys = append(keys, metadataRotatedBy)
+	}
+	if cfg.ExpiresAt {
+		keys = append(keys, metadataExpiresAt)
+	}
+	return keys
+}
+
+// stampMetadata writes the enabled bookkeeping fields into secret and returns their keys.
+func stampMetadata(secret map[string]interface{}, req models.RotationRequest, now time.Time) ([]string, error) {
+	cfg := req.KeyValueConfig.Metadata
+	now = now.UTC()
+
+	for _, key := range metadataKeys(cfg) {
+		switch key {
+		case metadataRotatedAt:
+			secret[key] = now.Format(time.RFC3339)
+		case metadataCredentialVersion:
+			// A clone is a new credential, the version copied from the source does not apply
+			var version int64
+			if req.Action != models.ActionClone {
+				var err error
+				if version, err = credentialVersion(secret[key]); err != nil {
+					return nil, err
+				}
+			}
+			secret[key] = version + 1
+		case metadataRotatedBy:
+			secret[key] = req.RequestedBy
+		case metadataExpiresAt:
+			if req.Policy == nil {
+				return nil, fmt.Errorf("%s requires a rotation policy", metadataExpiresAt)
+			}
+			interval, err := time.ParseDuration(req.Policy.Interval)
+			if err != nil {
+				return nil, fmt.Errorf("invalid policy interval: %w", err)
+			}
+			secret[key] = now.Add(interval).Format(time.RFC3339)
+		}
+	}
+	return metadataKeys(cfg), nil
+}
+
+// credentialVersion reads the current version from JSON numbers or file strings. Missing means 0.
+func credentialVersion(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return int64(v), nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", metadataCredentialVersion, v)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("invalid %s of type %T", metadataCredentialVersion, value)
+	}
+}
@@ -3,58 +3,161 @@ package rotator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
+	"github.com/darthlynx/secret-rotation-lambda/internal/certificate"
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/darthlynx/secret-rotation-lambda/internal/fileformat"
 	"github.com/darthlynx/secret-rotation-lambda/internal/generator"
+	"github.com/darthlynx/secret-rotation-lambda/internal/hasher"
+	"github.com/darthlynx/secret-rotation-lambda/internal/iam"
+	"github.com/darthlynx/secret-rotation-lambda/internal/keyring"
 	"github.com/darthlynx/secret-rotation-lambda/internal/models"
 	"github.com/darthlynx/secret-rotation-lambda/internal/secretsmanager"
+	"github.com/darthlynx/secret-rotation-lambda/internal/templating"
 	"github.com/darthlynx/secret-rotation-lambda/internal/validator"
+	"github.com/darthlynx/secret-rotation-lambda/pkg/jwks"
 )
 
+// defaultJWKSMaxKeys is the number of key generations kept when JWKSConfig.MaxKeys is not set.
+const defaultJWKSMaxKeys = 3
+
+// defaultKeyRingRetain is the number of decrypt-only keys kept when KeyRingConfig.Retain is not set.
+const defaultKeyRingRetain = 2
+
+// errRotationNotDue is returned by a rotation step when the secret is still valid and must be left unchanged.
+var errRotationNotDue = errors.New("rotation not due")
+
 // Rotator handles secret rotation logic.
 type Rotator struct {
-	smClient secretsmanager.Client
-	gen      generator.Generator
+	smClient   secretsmanager.Client
+	gen        generator.Generator
+	now        func() time.Time
+	connectors map[string]connector.Connector
+	iamClient  iam.Client
 }
 
-func New(smClient secretsmanager.Client, gen generator.Generator) *Rotator {
-	return &Rotator{
-		smClient: smClient,
-		gen:      gen,
+func New(smClient secretsmanager.Client, gen generator.Generator, opts ...Option) *Rotator {
+	r := &Rotator{
+		smClient:   smClient,
+		gen:        gen,
+		now:        time.Now,
+		connectors: map[string]connector.Connector{},
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 // RotateSecret performs the secret rotation based on the request.
 func (r *Rotator) RotateSecret(ctx context.Context, req models.RotationRequest) (*models.RotationResponse, error) {
 	if err := validator.ValidateRotationRequest(req); err != nil {
+		return &models.RotationResponse{
+			Success:     false,
+			SecretARN:   req.SecretARN,
+			ErrorMsg:    err.Error(),
+			FieldErrors: fieldErrors(err),
+		}, err
+	}
+
+	sch, err := r.loadSchema(ctx, req)
+	if err != nil {
 		return &models.RotationResponse{
 			Success:   false,
 			SecretARN: req.SecretARN,
-			ErrorMsg:  err.Error(),
+			ErrorMsg:  fmt.Sprintf("failed to load schema: %v", err),
 		}, err
 	}
 
+	if req.Action == models.ActionClone {
+		return r.cloneSecret(ctx, req, sch)
+	}
+
+	if sch != nil {
+		if err := r.checkExistingSecret(ctx, req, sch); err != nil {
+			return &models.RotationResponse{
+				Success:     false,
+				SecretARN:   req.SecretARN,
+				ErrorMsg:    err.Error(),
+				FieldErrors: fieldErrors(err),
+			}, err
+		}
+	}
+
+	// IAM creates the new value itself, so it goes through its own pending/promote flow
+	if req.SecretType == models.SecretTypeIAMAccessKey {
+		return r.rotateAccessKey(ctx, req, sch)
+	}
+
 	var newSecretValue string
-	var err error
 
 	switch req.SecretType {
 	case models.SecretTypePlaintext:
 		newSecretValue, err = r.rotatePlaintext(ctx, req)
+	case models.SecretTypeURI:
+		newSecretValue, err = r.rotateURI(ctx, req)
 	case models.SecretTypeKeyValue, models.SecretTypeJSON:
 		newSecretValue, err = r.rotateKeyValue(ctx, req)
+	case models.SecretTypeDotenv, models.SecretTypeYAML, models.SecretTypeINI, models.SecretTypeProperties:
+		newSecretValue, err = r.rotateFile(ctx, req)
+	case models.SecretTypeKeyPair:
+		newSecretValue, err = r.rotateKeyPair(ctx, req)
+	case models.SecretTypeCertificate:
+		newSecretValue, err = r.rotateCertificate(ctx, req)
+	case models.SecretTypeJWKS:
+		newSecretValue, err = r.rotateJWKS(ctx, req)
+	case models.SecretTypeKeyRing:
+		newSecretValue, err = r.rotateKeyRing(ctx, req)
 	default:
 		err = fmt.Errorf("unsupported secret type: %s", req.SecretType)
 	}
 
-	if err != nil {
+	if errors.Is(err, errRotationNotDue) {
 		return &models.RotationResponse{
-			Success:   false,
+			Success:   true,
 			SecretARN: req.SecretARN,
-			ErrorMsg:  err.Error(),
+			Skipped:   true,
+			Message:   err.Error(),
+		}, nil
+	}
+	if err == nil {
+		err = checkNewValue(sch, req.SecretType, newSecretValue)
+	}
+	if err != nil {
+		return &models.RotationResponse{
+			Success:     false,
+			SecretARN:   req.SecretARN,
+			ErrorMsg:    err.Error(),
+			FieldErrors: fieldErrors(err),
 		}, err
 	}
 
+	if len(r.connectors) > 0 {
+		if secret, err := parseSecretMap(newSecretValue); err == nil {
+			if conn := r.connectorFor(req.SecretType, secret); conn != nil {
+				return r.rotateWithConnector(ctx, req, conn, connector.Engine(secret), newSecretValue)
+			}
+		}
+	}
+
+	created := false
 	versionID, err := r.smClient.PutSecretValue(ctx, req.SecretARN, newSecretValue)
+	if errors.Is(err, secretsmanager.ErrSecretNotFound) && req.CreateIfMissing != nil {
+		var arn string
+		arn, versionID, err = r.createSecret(ctx, req, newSecretValue)
+		if err != nil {
+			return &models.RotationResponse{
+				Success:   false,
+				SecretARN: req.SecretARN,
+				ErrorMsg:  fmt.Sprintf("failed to create secret: %v", err),
+			}, err
+		}
+		req.SecretARN, created = arn, true
+	}
 	if err != nil {
 		return &models.RotationResponse{
 			Success:   false,
@@ -62,11 +165,25 @@ func (r *Rotator) RotateSecret(ctx context.Context, req models.RotationRequest)
 			ErrorMsg:  fmt.Sprintf("failed to update secret: %v", err),
 		}, err
 	}
+	if req.SecretType == models.SecretTypeJWKS && req.JWKSConfig.PublicSecretARN != "" {
+		// The ring is stored, so the response names its version for the caller to republish
+		if err := r.publishJWKS(ctx, req, newSecretValue); err != nil {
+			return &models.RotationResponse{
+				Success:   false,
+				SecretARN: req.SecretARN,
+				VersionID: versionID,
+				Created:   created,
+				ErrorMsg:  err.Error(),
+			}, err
+		}
+	}
 
 	return &models.RotationResponse{
 		Success:   true,
 		SecretARN: req.SecretARN,
 		VersionID: versionID,
+		Created:   created,
+		Warnings:  r.recordRotation(ctx, req, r.now()),
 	}, nil
 }
 
@@ -74,31 +191,226 @@ func (r *Rotator) rotatePlaintext(ctx context.Context, req models.RotationReques
 	return r.gen.Generate(req.GeneratorOpts)
 }
 
+func (r *Rotator) rotateURI(ctx context.Context, req models.RotationRequest) (string, error) {
+	existing, err := r.getExisting(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	password, err := r.gen.Generate(req.GeneratorOpts)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := replaceURIPassword(existing, password)
+	if err != nil {
+		return "", fmt.Errorf("failed to rotate URI password: %w", err)
+	}
+
+	return result, nil
+}
+
 func (r *Rotator) rotateKeyValue(ctx context.Context, req models.RotationRequest) (string, error) {
-	existing, err := r.smClient.GetSecretValue(ctx, req.SecretARN)
+	existingMap, err := r.getExistingMap(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to get existing secret: %w", err)
+		return "", err
 	}
 
-	var existingMap map[string]interface{}
-	if err := json.Unmarshal([]byte(existing), &existingMap); err != nil {
-		return "", fmt.Errorf("failed to parse existing secret as JSON: %w", err)
+	if _, err := r.rotateFields(req, existingMap); err != nil {
+		return "", err
+	}
+
+	result, err := json.Marshal(existingMap)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal updated secret: %w", err)
+	}
+
+	return string(result), nil
+}
+
+// rotateFile rotates values inside a whole configuration file stored as plaintext,
+// keeping comments, ordering and quoting intact.
+func (r *Rotator) rotateFile(ctx context.Context, req models.RotationRequest) (string, error) {
+	existing, err := r.getExisting(ctx, req)
+	if err != nil {
+		return "", err
 	}
 
-	keysToRotate := getKeys(existingMap)
+	doc, err := fileformat.Parse(string(req.SecretType), existing)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse existing secret as %s: %w", req.SecretType, err)
+	}
+
+	return r.rotateDocument(req, doc)
+}
+
+// rotateDocument applies the key-value rotation rules to a parsed configuration file and renders it.
+func (r *Rotator) rotateDocument(req models.RotationRequest, doc fileformat.Document) (string, error) {
+	values := make(map[string]interface{})
+	for _, key := range doc.Keys() {
+		values[key], _ = doc.Get(key)
+	}
+
+	written, err := r.rotateFields(req, values)
+	if err != nil {
+		return "", err
+	}
+	for _, key := range written {
+		if err := doc.Set(key, fmt.Sprint(values[key])); err != nil {
+			return "", fmt.Errorf("failed to update key %s: %w", key, err)
+		}
+	}
+
+	return doc.Render()
+}
+
+// rotateFields generates new values for the selected keys of secret in place, then refreshes
+// derived and templated fields. It returns every key it wrote, in a stable order.
+func (r *Rotator) rotateFields(req models.RotationRequest, secret map[string]interface{}) ([]string, error) {
+	var derived []models.DerivedField
+	var templates map[string]string
+	uriKeys := map[string]bool{}
+	if req.KeyValueConfig != nil {
+		derived = req.KeyValueConfig.DerivedFields
+		templates = req.KeyValueConfig.Templates
+		for _, k := range req.KeyValueConfig.URIKeys {
+			uriKeys[k] = true
+		}
+	}
+
+	keysToRotate := getKeys(secret, derivedKeys(req.KeyValueConfig))
 	if req.KeyValueConfig != nil && len(req.KeyValueConfig.KeysToRotate) > 0 {
 		keysToRotate = req.KeyValueConfig.KeysToRotate
+	} else if r.connectorFor(req.SecretType, secret) != nil {
+		// Host, username and the other connection fields must survive the rotation
+		keysToRotate = []string{connector.FieldPassword}
 	}
 
-	//newMap := make(map[string]interface{})
+	var written []string
+	// A clone only copies the structure, it never changes users in the target system
+	if conn := r.connectorFor(req.SecretType, secret); conn != nil && req.Action != models.ActionClone {
+		prepared, err := prepareStrategy(req, conn, secret, r.now())
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, prepared...)
+	}
+
+	rotated := make(map[string]bool, len(keysToRotate))
 	for _, key := range keysToRotate {
 		newValue, err := r.gen.Generate(req.GeneratorOpts)
 		if err != nil {
-			return "", fmt.Errorf("failed to generate value for key %s: %w", key, err)
+			return nil, fmt.Errorf("failed to generate value for key %s: %w", key, err)
+		}
+		if uriKeys[key] {
+			current, _ := secret[key].(string)
+			if newValue, err = replaceURIPassword(current, newValue); err != nil {
+				return nil, fmt.Errorf("failed to rotate URI password for key %s: %w", key, err)
+			}
+		}
+		secret[key] = newValue
+		rotated[key] = true
+	}
+	written = append(written, keysToRotate...)
+
+	derivedWritten, err := updateDerivedFields(secret, derived, rotated)
+	if err != nil {
+		return nil, err
+	}
+	written = append(written, derivedWritten...)
+
+	rendered, err := templating.Render(templates, secret)
+	if err != nil {
+		return nil, err
+	}
+	templateKeys := make([]string, 0, len(rendered))
+	for key, value := range rendered {
+		secret[key] = value
+		templateKeys = append(templateKeys, key)
+	}
+	sort.Strings(templateKeys)
+	written = append(written, templateKeys...)
+
+	if req.KeyValueConfig != nil && req.KeyValueConfig.Metadata != nil {
+		stamped, err := stampMetadata(secret, req, r.now())
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, stamped...)
+	}
+
+	return written, nil
+}
+
+func (r *Rotator) rotateKeyPair(ctx context.Context, req models.RotationRequest) (string, error) {
+	existingMap, err := r.getExistingMap(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := req.KeyPairConfig
+	kp, err := generator.GenerateKeyPair(cfg.Algorithm)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key pair: %w", err)
+	}
+
+	existingMap[fieldOrDefault(cfg.PrivateKeyField, "private_key")] = kp.PrivateKeyPEM
+	existingMap[fieldOrDefault(cfg.PublicKeyField, "public_key")] = kp.PublicKeyPEM
+	if cfg.SSHPublicKeyField != "" {
+		existingMap[cfg.SSHPublicKeyField] = kp.SSHPublicKey
+	}
+
+	result, err := json.Marshal(existingMap)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal updated secret: %w", err)
+	}
+
+	return string(result), nil
+}
+
+func (r *Rotator) rotateCertificate(ctx context.Context, req models.RotationRequest) (string, error) {
+	existingMap, err := r.getExistingMap(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := req.CertConfig
+	now := r.now()
+	if existingCert, ok := existingMap[certificate.FieldCertificate].(string); ok && existingCert != "" && cfg.RenewBefore != "" {
+		renewBefore, err := time.ParseDuration(cfg.RenewBefore)
+		if err != nil {
+			return "", fmt.Errorf("invalid renew_before: %w", err)
+		}
+		due, err := certificate.NeedsRenewal(existingCert, renewBefore, now)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse existing certificate: %w", err)
 		}
-		existingMap[key] = newValue
+		if !due {
+			return "", fmt.Errorf("%w: certificate is valid for more than %s", errRotationNotDue, cfg.RenewBefore)
+		}
+	}
+
+	caMap, err := r.getSecretMap(ctx, cfg.CASecretARN)
+	if err != nil {
+		return "", fmt.Errorf("failed to load CA secret: %w", err)
+	}
+	caCert, _ := caMap[certificate.FieldCertificate].(string)
+	caKey, _ := caMap[certificate.FieldPrivateKey].(string)
+	caChain, _ := caMap[certificate.FieldChain].(string)
+	ca, err := certificate.ParseCA(caCert, caKey, caChain)
+	if err != nil {
+		return "", err
+	}
+
+	bundle, err := certificate.Issue(ca, *cfg, now)
+	if err != nil {
+		return "", fmt.Errorf("failed to issue certificate: %w", err)
 	}
 
+	existingMap[certificate.FieldCertificate] = bundle.CertificatePEM
+	existingMap[certificate.FieldPrivateKey] = bundle.PrivateKeyPEM
+	existingMap[certificate.FieldChain] = bundle.ChainPEM
+
 	result, err := json.Marshal(existingMap)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal updated secret: %w", err)
@@ -107,10 +419,161 @@ func (r *Rotator) rotateKeyValue(ctx context.Context, req models.RotationRequest
 	return string(result), nil
 }
 
-func getKeys(m map[string]interface{}) []string {
+func (r *Rotator) rotateJWKS(ctx context.Context, req models.RotationRequest) (string, error) {
+	existing, err := r.getExisting(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	set, err := jwks.Parse(existing)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse existing secret as JWK Set: %w", err)
+	}
+
+	cfg := req.JWKSConfig
+	var delay time.Duration
+	if cfg.ActivationDelay != "" {
+		if delay, err = time.ParseDuration(cfg.ActivationDelay); err != nil {
+			return "", fmt.Errorf("invalid activation_delay: %w", err)
+		}
+	}
+	maxKeys := cfg.MaxKeys
+	if maxKeys == 0 {
+		maxKeys = defaultJWKSMaxKeys
+	}
+
+	if _, err := set.Rotate(cfg.Algorithm, r.now(), delay, maxKeys); err != nil {
+		return "", fmt.Errorf("failed to add signing key: %w", err)
+	}
+
+	result, err := json.Marshal(set)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal updated secret: %w", err)
+	}
+
+	return string(result), nil
+}
+
+// publishJWKS writes the public half of a stored ring to the public secret. It runs only once
+// the private ring has been stored, so verifiers never learn a key that was not kept;
+// activation_delay gives them time to pick it up before it signs.
+func (r *Rotator) publishJWKS(ctx context.Context, req models.RotationRequest, ring string) error {
+	public, err := jwks.PublicJWKS(ring)
+	if err != nil {
+		return fmt.Errorf("failed to build public JWK Set: %w", err)
+	}
+	if _, err := r.smClient.PutSecretValue(ctx, req.JWKSConfig.PublicSecretARN, public); err != nil {
+		return fmt.Errorf("failed to publish public JWK Set: %w", err)
+	}
+	return nil
+}
+
+func (r *Rotator) rotateKeyRing(ctx context.Context, req models.RotationRequest) (string, error) {
+	existing, err := r.getExisting(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	keys, err := keyring.Parse(existing)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse existing secret as key ring: %w", err)
+	}
+
+	retain := defaultKeyRingRetain
+	if req.KeyRingConfig.Retain != nil {
+		retain = *req.KeyRingConfig.Retain
+	}
+
+	keys, err = keyring.Rotate(keys, req.KeyRingConfig.Algorithm, retain, r.now())
+	if err != nil {
+		return "", fmt.Errorf("failed to rotate key ring: %w", err)
+	}
+
+	result, err := json.Marshal(keys)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal updated secret: %w", err)
+	}
+
+	return string(result), nil
+}
+
+// getSecretMap fetches the secret and parses it as a JSON object.
+func (r *Rotator) getSecretMap(ctx context.Context, secretARN string) (map[string]interface{}, error) {
+	existing, err := r.smClient.GetSecretValue(ctx, secretARN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing secret: %w", err)
+	}
+
+	return parseSecretMap(existing)
+}
+
+// parseSecretMap parses a secret value as a JSON object.
+func parseSecretMap(existing string) (map[string]interface{}, error) {
+	var existingMap map[string]interface{}
+	if err := json.Unmarshal([]byte(existing), &existingMap); err != nil {
+		return nil, fmt.Errorf("failed to parse existing secret as JSON: %w", err)
+	}
+
+	return existingMap, nil
+}
+
+func fieldOrDefault(field, def string) string {
+	if field == "" {
+		return def
+	}
+	return field
+}
+
+// updateDerivedFields recomputes the verifiers whose source key was rotated and returns their keys.
+func updateDerivedFields(secret map[string]interface{}, fields []models.DerivedField, rotated map[string]bool) ([]string, error) {
+	var written []string
+	for _, f := range fields {
+		if !rotated[f.Source] {
+			continue
+		}
+		password, ok := secret[f.Source].(string)
+		if !ok {
+			return nil, fmt.Errorf("derived field %s: source key %s is not a string", f.Key, f.Source)
+		}
+		username, _ := secret[fieldOrDefault(f.UsernameKey, "username")].(string)
+
+		value, err := hasher.Hash(f.Algorithm, password, username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute derived field %s: %w", f.Key, err)
+		}
+		secret[f.Key] = value
+		written = append(written, f.Key)
+	}
+	return written, nil
+}
+
+// derivedKeys returns the keys computed by the rotator itself, which are never rotated with generated values.
+func derivedKeys(cfg *models.KeyValueConfig) map[string]bool {
+	keys := map[string]bool{}
+	if cfg == nil {
+		return keys
+	}
+	for _, f := range cfg.DerivedFields {
+		keys[f.Key] = true
+	}
+	for k := range cfg.Templates {
+		keys[k] = true
+	}
+	for _, k := range metadataKeys(cfg.Metadata) {
+		keys[k] = true
+	}
+	return keys
+}
+
+// getKeys returns the keys of m except the excluded ones, sorted so that values generated
+// for them, and templates reading those values, do not depend on map iteration order.
+func getKeys(m map[string]interface{}, exclude map[string]bool) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
-		keys = append(keys, k)
+		if !exclude[k] {
+			keys = append(keys, k)
+		}
 	}
+	sort.Strings(keys)
 	return keys
 }
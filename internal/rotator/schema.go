@@ -0,0 +1,116 @@
+package rotator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/fileformat"
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+	"github.com/darthlynx/secret-rotation-lambda/internal/schema"
+	"github.com/darthlynx/secret-rotation-lambda/internal/secretsmanager"
+	"github.com/darthlynx/secret-rotation-lambda/internal/validator"
+)
+
+// schemaTag names the secret tag holding the ARN of the secret that contains its schema.
+const schemaTag = "rotation-schema"
+
+// maxSecretSize is the Secrets Manager limit for a secret value in bytes.
+const maxSecretSize = 65536
+
+// loadSchema compiles the schema linked to the secret, or returns nil when none is configured.
+func (r *Rotator) loadSchema(ctx context.Context, req models.RotationRequest) (*schema.Schema, error) {
+	cfg := req.Schema
+	if cfg == nil {
+		return nil, nil
+	}
+
+	text, schemaARN := string(cfg.Inline), cfg.SecretARN
+	if cfg.FromTag {
+		// A clone target does not exist yet, so it inherits the schema of its source
+		tagged := req.SecretARN
+		if req.Action == models.ActionClone {
+			tagged = req.CloneConfig.SourceSecretARN
+		}
+		tags, err := r.smClient.GetTags(ctx, tagged)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret tags: %w", err)
+		}
+		if schemaARN = tags[schemaTag]; schemaARN == "" {
+			return nil, fmt.Errorf("secret has no %s tag", schemaTag)
+		}
+	}
+	if schemaARN != "" {
+		var err error
+		if text, err = r.smClient.GetSecretValue(ctx, schemaARN); err != nil {
+			return nil, fmt.Errorf("failed to get schema secret: %w", err)
+		}
+	}
+
+	return schema.Compile(text)
+}
+
+// checkExistingSecret refuses to rotate a secret that already violates its schema.
+// A secret that is about to be created has nothing to check.
+func (r *Rotator) checkExistingSecret(ctx context.Context, req models.RotationRequest, sch *schema.Schema) error {
+	existing, err := r.smClient.GetSecretValue(ctx, req.SecretARN)
+	if errors.Is(err, secretsmanager.ErrSecretNotFound) && req.CreateIfMissing != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get existing secret: %w", err)
+	}
+
+	if err := validateAgainstSchema(sch, req.SecretType, existing); err != nil {
+		return fmt.Errorf("existing secret does not match schema: %w", err)
+	}
+	return nil
+}
+
+// checkNewValue runs the last checks before a value is written: the size limit and the schema, if any.
+func checkNewValue(sch *schema.Schema, secretType models.SecretType, value string) error {
+	if len(value) > maxSecretSize {
+		return fmt.Errorf("new secret value is %d bytes, exceeding the %d byte limit", len(value), maxSecretSize)
+	}
+	if sch == nil {
+		return nil
+	}
+	if err := validateAgainstSchema(sch, secretType, value); err != nil {
+		return fmt.Errorf("new secret value does not match schema: %w", err)
+	}
+	return nil
+}
+
+// validateAgainstSchema validates JSON secrets as documents, configuration files as an object
+// of their dotted keys, and plaintext secrets as a single string.
+func validateAgainstSchema(sch *schema.Schema, secretType models.SecretType, value string) error {
+	switch secretType {
+	case models.SecretTypePlaintext, models.SecretTypeURI:
+		return sch.Validate(value)
+	case models.SecretTypeDotenv, models.SecretTypeYAML, models.SecretTypeINI, models.SecretTypeProperties:
+		doc, err := fileformat.Parse(string(secretType), value)
+		if err != nil {
+			return err
+		}
+		values := make(map[string]any)
+		for _, key := range doc.Keys() {
+			values[key], _ = doc.Get(key)
+		}
+		return sch.Validate(values)
+	default:
+		return sch.ValidateJSON(value)
+	}
+}
+
+// fieldErrors extracts the field-level details of a request or schema violation for the response.
+func fieldErrors(err error) []models.FieldError {
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		return invalid
+	}
+	var violation *schema.ViolationError
+	if errors.As(err, &violation) {
+		return violation.Fields
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package rotator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+)
+
+// cloneSuffix names the second user of the alternating-users strategy.
+const cloneSuffix = "_clone"
+
+// strategy returns the connector strategy of the request.
+func strategy(req models.RotationRequest) models.ConnectorStrategy {
+	if req.Connector == nil || req.Connector.Strategy == "" {
+		return models.StrategySingleUser
+	}
+	return req.Connector.Strategy
+}
+
+// alternateUser returns the user that takes over from username: app_user <-> app_user_clone.
+func alternateUser(username string) string {
+	if base, ok := strings.CutSuffix(username, cloneSuffix); ok {
+		return base
+	}
+	return username + cloneSuffix
+}
+
+// ephemeralUsername generates the name of a new ephemeral user: <prefix>_<timestamp>_<random>.
+// The timestamp keeps names ordered, the random part keeps retried rotations from colliding.
+func ephemeralUsername(prefix string, now time.Time) (string, error) {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate username: %w", err)
+	}
+	return prefix + "_" + now.UTC().Format("20060102150405") + "_" + hex.EncodeToString(suffix), nil
+}
+
+// prepareStrategy updates the connection fields of a secret before its new values are
+// generated, and returns the keys it changed.
+func prepareStrategy(req models.RotationRequest, conn connector.Connector, secret map[string]interface{}, now time.Time) ([]string, error) {
+	switch strategy(req) {
+	case models.StrategyAlternatingUsers:
+		if _, ok := conn.(connector.UserCloner); !ok {
+			return nil, fmt.Errorf("engine %s does not support the %s strategy", connector.Engine(secret), models.StrategyAlternatingUsers)
+		}
+		// Creating the clone and changing the inactive user's password need admin rights
+		if master, _ := secret[connector.FieldMasterARN].(string); master == "" {
+			return nil, fmt.Errorf("the %s strategy requires %s", models.StrategyAlternatingUsers, connector.FieldMasterARN)
+		}
+		username, _ := secret[connector.FieldUsername].(string)
+		if username == "" {
+			return nil, errors.New("secret has no " + connector.FieldUsername)
+		}
+		secret[connector.FieldUsername] = alternateUser(username)
+		return []string{connector.FieldUsername}, nil
+	case models.StrategyEphemeralUsers:
+		if _, ok := conn.(connector.UserManager); !ok {
+			return nil, fmt.Errorf("engine %s does not support the %s strategy", connector.Engine(secret), models.StrategyEphemeralUsers)
+		}
+		if master, _ := secret[connector.FieldMasterARN].(string); master == "" {
+			return nil, fmt.Errorf("the %s strategy requires %s", models.StrategyEphemeralUsers, connector.FieldMasterARN)
+		}
+		username, err := ephemeralUsername(req.Connector.UsernamePrefix, now)
+		if err != nil {
+			return nil, err
+		}
+		// The replaced user stays valid until the next rotation, which drops it
+		if current, _ := secret[connector.FieldUsername].(string); current != "" {
+			secret[connector.FieldPreviousUsername] = current
+		} else {
+			delete(secret, connector.FieldPreviousUsername)
+		}
+		secret[connector.FieldUsername] = username
+		return []string{connector.FieldUsername, connector.FieldPreviousUsername}, nil
+	default:
+		return nil, nil
+	}
+}
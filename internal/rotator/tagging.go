@@ -0,0 +1,96 @@
+package rotator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+)
+
+// Tags written when TaggingConfig.Tags is set.
+const (
+	tagLastRotatedAt   = "last-rotated-at"
+	tagLastRotatedBy   = "last-rotated-by"
+	tagGenerator       = "rotation-generator"
+	tagNextRotationDue = "next-rotation-due"
+
+	maxTagValueLength = 256
+)
+
+// recordRotation tags the secret and updates its description after a successful update.
+// The new version is already stored at this point, so failures are reported as warnings.
+func (r *Rotator) recordRotation(ctx context.Context, req models.RotationRequest, now time.Time) []string {
+	cfg := req.Tagging
+	if cfg == nil {
+		return nil
+	}
+
+	var warnings []string
+	if cfg.Tags {
+		now = now.UTC()
+		tags := map[string]string{
+			tagLastRotatedAt: now.Format(time.RFC3339),
+			tagGenerator:     generatorName(req),
+		}
+		if req.RequestedBy != "" {
+			tags[tagLastRotatedBy] = sanitizeTagValue(req.RequestedBy)
+		}
+		if req.Policy != nil {
+			if interval, err := time.ParseDuration(req.Policy.Interval); err == nil {
+				tags[tagNextRotationDue] = now.Add(interval).Format(time.RFC3339)
+			}
+		}
+		if err := r.smClient.TagSecret(ctx, req.SecretARN, tags); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to tag secret: %v", err))
+		}
+	}
+
+	if cfg.Description != "" {
+		if err := r.smClient.UpdateDescription(ctx, req.SecretARN, cfg.Description); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to update description: %v", err))
+		}
+	}
+
+	return warnings
+}
+
+// generatorName describes what produced the new value, e.g. "password" or "keypair:ed25519".
+func generatorName(req models.RotationRequest) string {
+	switch {
+	case req.SecretType == models.SecretTypeKeyPair && req.KeyPairConfig != nil:
+		return "keypair:" + string(req.KeyPairConfig.Algorithm)
+	case req.SecretType == models.SecretTypeCertificate && req.CertConfig != nil:
+		return "certificate:" + string(req.CertConfig.KeyAlgorithm)
+	case req.SecretType == models.SecretTypeJWKS && req.JWKSConfig != nil:
+		return "jwks:" + req.JWKSConfig.Algorithm
+	case req.SecretType == models.SecretTypeKeyRing && req.KeyRingConfig != nil:
+		return "keyring:" + req.KeyRingConfig.Algorithm
+	case req.SecretType == models.SecretTypeIAMAccessKey:
+		// IAM creates the key, the generator options are not used
+		return string(models.SecretTypeIAMAccessKey)
+	case req.GeneratorOpts.Format != "":
+		return string(req.GeneratorOpts.Format)
+	default:
+		return string(models.GeneratorFormatPassword)
+	}
+}
+
+// sanitizeTagValue replaces characters Secrets Manager rejects in tag values and enforces the length limit.
+func sanitizeTagValue(v string) string {
+	v = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune(" _.:/=+-@", r):
+			return r
+		default:
+			return '_'
+		}
+	}, v)
+	if len(v) > maxTagValueLength {
+		v = v[:maxTagValueLength]
+	}
+	return v
+}
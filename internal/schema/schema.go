@@ -0,0 +1,149 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+)
+
+// resourceURL names the schema document inside the compiler, it is never fetched.
+const resourceURL = "mem://secret-schema.json"
+
+// Schema is a compiled JSON Schema describing the structure of a secret.
+type Schema struct {
+	compiled *jsonschema.Schema
+}
+
+// ViolationError lists the fields of a document that do not match the schema.
+type ViolationError struct {
+	Fields []models.FieldError
+}
+
+func (e *ViolationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		field := f.Field
+		if field == "" {
+			field = "(root)"
+		}
+		msgs = append(msgs, field+": "+f.Message)
+	}
+	return "schema violation: " + strings.Join(msgs, "; ")
+}
+
+// noRemoteLoader stops $ref from reading files or URLs, a schema must be self-contained.
+type noRemoteLoader struct{}
+
+func (noRemoteLoader) Load(url string) (any, error) {
+	return nil, fmt.Errorf("external schema references are not supported: %s", url)
+}
+
+// Compile parses a JSON Schema document. Formats such as "uri" and "email" are asserted.
+func Compile(text string) (*Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	c := jsonschema.NewCompiler()
+	c.UseLoader(noRemoteLoader{})
+	c.AssertFormat()
+	if err := c.AddResource(resourceURL, doc); err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+	compiled, err := c.Compile(resourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema: %w", err)
+	}
+
+	return &Schema{compiled: compiled}, nil
+}
+
+// ValidateJSON validates a JSON document against the schema.
+func (s *Schema) ValidateJSON(text string) error {
+	doc, err := jsonschema.UnmarshalJSON(strings.NewReader(text))
+	if err != nil {
+		// The parser error quotes the offending input, which may be part of the secret
+		return &ViolationError{Fields: []models.FieldError{{Code: "invalid_json", Message: "not a valid JSON document"}}}
+	}
+	return s.Validate(doc)
+}
+
+// Validate validates a decoded value, with objects as map[string]any.
+func (s *Schema) Validate(doc any) error {
+	err := s.compiled.Validate(doc)
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+
+	var fields []models.FieldError
+	for _, unit := range verr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		code := keyword(unit.KeywordLocation)
+		fields = append(fields, models.FieldError{
+			Field:   fieldPath(unit.InstanceLocation),
+			Code:    code,
+			Message: message(code, unit.Error.Kind),
+		})
+	}
+	return &ViolationError{Fields: fields}
+}
+
+// message describes a violation from the schema side only. The library's own text quotes the
+// failing value, which for a secret is the secret itself.
+func message(code string, k jsonschema.ErrorKind) string {
+	switch k := k.(type) {
+	case *kind.Required:
+		return "missing properties " + quoteAll(k.Missing)
+	case *kind.Type:
+		return "must be of type " + strings.Join(k.Want, " or ")
+	case *kind.Format:
+		return "is not a valid " + k.Want
+	case *kind.Pattern:
+		return fmt.Sprintf("does not match pattern %q", k.Want)
+	case *kind.MinLength:
+		return fmt.Sprintf("must be at least %d characters", k.Want)
+	case *kind.MaxLength:
+		return fmt.Sprintf("must be at most %d characters", k.Want)
+	case *kind.Enum, *kind.Const:
+		return "is not one of the allowed values"
+	case *kind.AdditionalProperties:
+		return "additional properties " + quoteAll(k.Properties) + " not allowed"
+	}
+	return "does not satisfy " + code
+}
+
+func quoteAll(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = "'" + n + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// fieldPath turns a JSON pointer such as "/database/port" into "database.port".
+func fieldPath(pointer string) string {
+	if pointer == "" {
+		return ""
+	}
+	parts := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, p := range parts {
+		parts[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// keyword returns the failing schema keyword, the last token of the keyword location.
+func keyword(location string) string {
+	if i := strings.LastIndexByte(location, '/'); i >= 0 {
+		return location[i+1:]
+	}
+	return location
+}
@@ -0,0 +1,118 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["username", "password"],
+	"properties": {
+		"username": {"type": "string"},
+		"password": {"type": "string", "minLength": 12},
+		"url": {"type": "string", "format": "uri"},
+		"database": {
+			"type": "object",
+			"properties": {"port": {"type": "integer"}}
+		}
+	}
+}`
+
+func TestValidateJSON(t *testing.T) {
+	sch, err := Compile(testSchema)
+	if err != nil {
+		t.Fatalf("Compile() error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		doc   string
+		want  []models.FieldError
+		valid bool
+	}{
+		{
+			name:  "valid",
+			doc:   `{"username": "app", "password": "long-enough-pass", "url": "https://example.com"}`,
+			valid: true,
+		},
+		{
+			name: "missing key",
+			doc:  `{"password": "long-enough-pass"}`,
+			want: []models.FieldError{{Field: "", Code: "required"}},
+		},
+		{
+			name: "short password and bad nested type",
+			doc:  `{"username": "app", "password": "short", "database": {"port": "5432"}}`,
+			want: []models.FieldError{{Field: "password", Code: "minLength"}, {Field: "database.port", Code: "type"}},
+		},
+		{
+			name: "format asserted",
+			doc:  `{"username": "app", "password": "long-enough-pass", "url": "not a uri"}`,
+			want: []models.FieldError{{Field: "url", Code: "format"}},
+		},
+		{
+			name: "not JSON",
+			doc:  `{`,
+			want: []models.FieldError{{Code: "invalid_json"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sch.ValidateJSON(tt.doc)
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("ValidateJSON() error: %v", err)
+				}
+				return
+			}
+
+			var violation *ViolationError
+			if !errors.As(err, &violation) {
+				t.Fatalf("ValidateJSON() error = %v, want ViolationError", err)
+			}
+			var got []models.FieldError
+			for _, f := range violation.Fields {
+				if f.Message == "" {
+					t.Errorf("field %q has no message", f.Field)
+				}
+				got = append(got, models.FieldError{Field: f.Field, Code: f.Code})
+			}
+			if !sameFields(got, tt.want) {
+				t.Errorf("fields = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, text := range []string{
+		`not json`,
+		`{"type": 12}`,
+		`{"$ref": "https://example.com/schema.json"}`,
+	} {
+		if _, err := Compile(text); err == nil {
+			t.Errorf("Compile(%q) expected error, got nil", text)
+		}
+	}
+}
+
+// sameFields compares field errors ignoring order.
+func sameFields(got, want []models.FieldError) bool {
+	count := map[models.FieldError]int{}
+	for _, f := range got {
+		count[f]++
+	}
+	for _, f := range want {
+		count[f]--
+	}
+	for _, n := range count {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
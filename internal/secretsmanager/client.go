@@ -3,17 +3,36 @@ package secretsmanager
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
 )
 
+// ErrSecretNotFound is returned when the secret does not exist.
+var ErrSecretNotFound = errors.New("secret not found")
+
 // Client defines the interface for AWS Secrets Manager operations.
 type Client interface {
 	GetSecretValue(ctx context.Context, secretARN string) (string, error)
 	PutSecretValue(ctx context.Context, secretARN, secretValue string) (string, error)
+	TagSecret(ctx context.Context, secretARN string, tags map[string]string) error
+	UpdateDescription(ctx context.Context, secretARN, description string) error
+	CreateSecret(ctx context.Context, props models.SecretProperties, secretValue string) (arn, versionID string, err error)
+	GetTags(ctx context.Context, secretARN string) (map[string]string, error)
+	PutPendingSecretValue(ctx context.Context, secretARN, secretValue string) (string, error)
+	PromoteVersion(ctx context.Context, secretARN, versionID string) error
 }
 
+// Staging labels used by Secrets Manager rotation.
+const (
+	StageCurrent = "AWSCURRENT"
+	StagePending = "AWSPENDING"
+)
+
 // SecretsManagerClient implements the Client interface.
 type SecretsManagerClient struct {
 	client *secretsmanager.Client
@@ -34,7 +53,7 @@ func (c *SecretsManagerClient) GetSecretValue(ctx context.Context, secretARN str
 
 	result, err := c.client.GetSecretValue(ctx, input)
 	if err != nil {
-		return "", err
+		return "", wrapNotFound(err)
 	}
 
 	if result.SecretString != nil {
@@ -53,12 +72,145 @@ func (c *SecretsManagerClient) PutSecretValue(ctx context.Context, secretARN, se
 
 	result, err := c.client.PutSecretValue(ctx, input)
 	if err != nil {
-		return "", err
+		return "", wrapNotFound(err)
 	}
 
 	return *result.VersionId, nil
 }
 
+// TagSecret adds or overwrites tags on the secret.
+func (c *SecretsManagerClient) TagSecret(ctx context.Context, secretARN string, tags map[string]string) error {
+	input := &secretsmanager.TagResourceInput{
+		SecretId: aws.String(secretARN),
+	}
+	for k, v := range tags {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	_, err := c.client.TagResource(ctx, input)
+	return err
+}
+
+// UpdateDescription replaces the secret description without creating a new version.
+func (c *SecretsManagerClient) UpdateDescription(ctx context.Context, secretARN, description string) error {
+	input := &secretsmanager.UpdateSecretInput{
+		SecretId:    aws.String(secretARN),
+		Description: aws.String(description),
+	}
+
+	_, err := c.client.UpdateSecret(ctx, input)
+	return err
+}
+
+// CreateSecret creates a new secret with an initial value and returns its ARN and version ID.
+func (c *SecretsManagerClient) CreateSecret(ctx context.Context, props models.SecretProperties, secretValue string) (string, string, error) {
+	input := &secretsmanager.CreateSecretInput{
+		Name:         aws.String(props.Name),
+		SecretString: aws.String(secretValue),
+	}
+	if props.KMSKeyID != "" {
+		input.KmsKeyId = aws.String(props.KMSKeyID)
+	}
+	if props.Description != "" {
+		input.Description = aws.String(props.Description)
+	}
+	for k, v := range props.Tags {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	for _, region := range props.ReplicaRegions {
+		input.AddReplicaRegions = append(input.AddReplicaRegions, types.ReplicaRegionType{Region: aws.String(region)})
+	}
+
+	result, err := c.client.CreateSecret(ctx, input)
+	if err != nil {
+		return "", "", err
+	}
+
+	return aws.ToString(result.ARN), aws.ToString(result.VersionId), nil
+}
+
+// PutPendingSecretValue stores a new version labelled AWSPENDING, leaving AWSCURRENT unchanged.
+func (c *SecretsManagerClient) PutPendingSecretValue(ctx context.Context, secretARN, secretValue string) (string, error) {
+	input := &secretsmanager.PutSecretValueInput{
+		SecretId:      aws.String(secretARN),
+		SecretString:  aws.String(secretValue),
+		VersionStages: []string{StagePending},
+	}
+
+	result, err := c.client.PutSecretValue(ctx, input)
+	if err != nil {
+		return "", wrapNotFound(err)
+	}
+
+	return *result.VersionId, nil
+}
+
+// PromoteVersion moves AWSCURRENT to the given version and clears its AWSPENDING label.
+// Secrets Manager moves AWSPREVIOUS to the version that was current.
+func (c *SecretsManagerClient) PromoteVersion(ctx context.Context, secretARN, versionID string) error {
+	desc, err := c.client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{SecretId: aws.String(secretARN)})
+	if err != nil {
+		return wrapNotFound(err)
+	}
+
+	var currentID string
+	for id, stages := range desc.VersionIdsToStages {
+		for _, stage := range stages {
+			if stage == StageCurrent {
+				currentID = id
+			}
+		}
+	}
+
+	if currentID != versionID {
+		input := &secretsmanager.UpdateSecretVersionStageInput{
+			SecretId:        aws.String(secretARN),
+			VersionStage:    aws.String(StageCurrent),
+			MoveToVersionId: aws.String(versionID),
+		}
+		if currentID != "" {
+			input.RemoveFromVersionId = aws.String(currentID)
+		}
+		if _, err := c.client.UpdateSecretVersionStage(ctx, input); err != nil {
+			return err
+		}
+	}
+
+	_, err = c.client.UpdateSecretVersionStage(ctx, &secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:            aws.String(secretARN),
+		VersionStage:        aws.String(StagePending),
+		RemoveFromVersionId: aws.String(versionID),
+	})
+	return err
+}
+
+// GetTags returns the tags of the secret.
+func (c *SecretsManagerClient) GetTags(ctx context.Context, secretARN string) (map[string]string, error) {
+	input := &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretARN),
+	}
+
+	result, err := c.client.DescribeSecret(ctx, input)
+	if err != nil {
+		return nil, wrapNotFound(err)
+	}
+
+	tags := make(map[string]string, len(result.Tags))
+	for _, tag := range result.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags, nil
+}
+
+// wrapNotFound marks ResourceNotFoundException errors with ErrSecretNotFound.
+func wrapNotFound(err error) error {
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return fmt.Errorf("%w: %w", ErrSecretNotFound, err)
+	}
+	return err
+}
+
 // MergeKeyValueSecret merges new keys into existing key-value secret.
 func MergeKeyValueSecret(existing, newValues string, keysToRotate []string) (string, error) {
 	var existingMap, newMap map[string]interface{}
@@ -3,12 +3,20 @@ package secretsmanager
 import (
 	"context"
 	"errors"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
 )
 
 // MockClient is a mock implementation of the Client interface for testing.
 type MockClient struct {
-	GetSecretValueFunc func(ctx context.Context, secretARN string) (string, error)
-	PutSecretValueFunc func(ctx context.Context, secretARN, secretValue string) (string, error)
+	GetSecretValueFunc        func(ctx context.Context, secretARN string) (string, error)
+	PutSecretValueFunc        func(ctx context.Context, secretARN, secretValue string) (string, error)
+	TagSecretFunc             func(ctx context.Context, secretARN string, tags map[string]string) error
+	UpdateDescriptionFunc     func(ctx context.Context, secretARN, description string) error
+	CreateSecretFunc          func(ctx context.Context, props models.SecretProperties, secretValue string) (string, string, error)
+	GetTagsFunc               func(ctx context.Context, secretARN string) (map[string]string, error)
+	PutPendingSecretValueFunc func(ctx context.Context, secretARN, secretValue string) (string, error)
+	PromoteVersionFunc        func(ctx context.Context, secretARN, versionID string) error
 }
 
 // GetSecretValue calls the mock function.
@@ -26,3 +34,51 @@ func (m *MockClient) PutSecretValue(ctx context.Context, secretARN, secretValue
 	}
 	return "", errors.New("PutSecretValueFunc not implemented")
 }
+
+// TagSecret calls the mock function.
+func (m *MockClient) TagSecret(ctx context.Context, secretARN string, tags map[string]string) error {
+	if m.TagSecretFunc != nil {
+		return m.TagSecretFunc(ctx, secretARN, tags)
+	}
+	return errors.New("TagSecretFunc not implemented")
+}
+
+// UpdateDescription calls the mock function.
+func (m *MockClient) UpdateDescription(ctx context.Context, secretARN, description string) error {
+	if m.UpdateDescriptionFunc != nil {
+		return m.UpdateDescriptionFunc(ctx, secretARN, description)
+	}
+	return errors.New("UpdateDescriptionFunc not implemented")
+}
+
+// CreateSecret calls the mock function.
+func (m *MockClient) CreateSecret(ctx context.Context, props models.SecretProperties, secretValue string) (string, string, error) {
+	if m.CreateSecretFunc != nil {
+		return m.CreateSecretFunc(ctx, props, secretValue)
+	}
+	return "", "", errors.New("CreateSecretFunc not implemented")
+}
+
+// GetTags calls the mock function.
+func (m *MockClient) GetTags(ctx context.Context, secretARN string) (map[string]string, error) {
+	if m.GetTagsFunc != nil {
+		return m.GetTagsFunc(ctx, secretARN)
+	}
+	return nil, errors.New("GetTagsFunc not implemented")
+}
+
+// PutPendingSecretValue calls the mock function.
+func (m *MockClient) PutPendingSecretValue(ctx context.Context, secretARN, secretValue string) (string, error) {
+	if m.PutPendingSecretValueFunc != nil {
+		return m.PutPendingSecretValueFunc(ctx, secretARN, secretValue)
+	}
+	return "", errors.New("PutPendingSecretValueFunc not implemented")
+}
+
+// PromoteVersion calls the mock function.
+func (m *MockClient) PromoteVersion(ctx context.Context, secretARN, versionID string) error {
+	if m.PromoteVersionFunc != nil {
+		return m.PromoteVersionFunc(ctx, secretARN, versionID)
+	}
+	return errors.New("PromoteVersionFunc not implemented")
+}
@@ -0,0 +1,42 @@
+package templating
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// funcs are available in addition to the text/template builtins such as urlquery.
+var funcs = template.FuncMap{
+	"pathescape":  url.PathEscape,
+	"queryescape": url.QueryEscape,
+	"userinfo": func(user, password string) string {
+		return url.UserPassword(user, password).String()
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// Parse compiles a template for a derived field. Referencing a missing key is an error.
+func Parse(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+}
+
+// Render evaluates every template against data and returns the results keyed by target.
+// All templates see the same data, so they cannot depend on each other's output.
+func Render(templates map[string]string, data map[string]interface{}) (map[string]string, error) {
+	results := make(map[string]string, len(templates))
+	for key, text := range templates {
+		tmpl, err := Parse(key, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template for %s: %w", key, err)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("failed to render template for %s: %w", key, err)
+		}
+		results[key] = b.String()
+	}
+	return results, nil
+}
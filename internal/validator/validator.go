@@ -1,55 +1,520 @@
 package validator
 
 import (
-	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/darthlynx/secret-rotation-lambda/internal/connector"
+	"github.com/darthlynx/secret-rotation-lambda/internal/generator"
+	"github.com/darthlynx/secret-rotation-lambda/internal/hasher"
+	"github.com/darthlynx/secret-rotation-lambda/internal/keyring"
 	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+	"github.com/darthlynx/secret-rotation-lambda/internal/templating"
+	"github.com/darthlynx/secret-rotation-lambda/pkg/apitoken"
+	"github.com/darthlynx/secret-rotation-lambda/pkg/jwks"
 )
 
+// Codes reported in models.FieldError.Code.
+const (
+	CodeRequired    = "required"     // value is missing
+	CodeInvalid     = "invalid"      // value cannot be parsed or is not one of the allowed values
+	CodeOutOfRange  = "out_of_range" // number or duration outside the allowed range
+	CodeConflict    = "conflict"     // value contradicts another field
+	CodeUnsupported = "unsupported"  // option is not available for this secret type or action
+)
+
+// ValidationErrors lists every problem found in a request.
+type ValidationErrors []models.FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, f := range e {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+// collector accumulates field errors while a request is checked.
+type collector struct {
+	errs ValidationErrors
+}
+
+func (c *collector) add(field, code, format string, args ...any) {
+	c.errs = append(c.errs, models.FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateRotationRequest checks the whole request and returns ValidationErrors with every
+// problem found, or nil when the request is valid.
 func ValidateRotationRequest(req models.RotationRequest) error {
-	if err := validateSecretARN(req.SecretARN); err != nil {
-		return err
+	c := &collector{}
+
+	if req.Action == models.ActionClone {
+		// The clone target does not exist yet and is usually given by name
+		if req.SecretARN == "" {
+			c.add("secret_arn", CodeRequired, "cannot be empty")
+		}
+	} else {
+		validateSecretARN(c, "secret_arn", req.SecretARN)
 	}
 
-	if err := validateSecretType(req.SecretType); err != nil {
-		return err
+	validSecretType := validateSecretType(c, req.SecretType)
+	validateGeneratorOptions(c, req.GeneratorOpts)
+
+	if req.Tagging != nil && req.Tagging.Tags && req.Policy != nil {
+		validatePolicy(c, req.Policy)
+	}
+	validateAction(c, req)
+	if req.Schema != nil {
+		validateSchemaConfig(c, req)
+	}
+	if req.Connector != nil {
+		validateConnectorConfig(c, req.Connector)
+	}
+	if req.CreateIfMissing != nil {
+		validateCreateConfig(c, req.SecretType, req.CreateIfMissing)
+		if req.Connector != nil {
+			c.add("create_if_missing", CodeConflict, "cannot be combined with connector")
+		}
 	}
 
-	if req.SecretType == models.SecretTypeKeyValue || req.SecretType == models.SecretTypeJSON {
-		if err := validateKeyValueConfig(req.KeyValueConfig); err != nil {
-			return err
+	if validSecretType {
+		switch req.SecretType {
+		case models.SecretTypeKeyValue, models.SecretTypeJSON,
+			models.SecretTypeDotenv, models.SecretTypeYAML, models.SecretTypeINI, models.SecretTypeProperties:
+			validateKeyValueConfig(c, req.KeyValueConfig, req.GeneratorOpts)
+			if req.KeyValueConfig != nil && req.KeyValueConfig.Metadata != nil && req.KeyValueConfig.Metadata.ExpiresAt {
+				if req.Policy == nil {
+					c.add("policy", CodeRequired, "is required when expires_at metadata is enabled")
+				} else {
+					validatePolicy(c, req.Policy)
+				}
+			}
+		case models.SecretTypeKeyPair:
+			validateKeyPairConfig(c, req.KeyPairConfig)
+		case models.SecretTypeCertificate:
+			validateCertificateConfig(c, req.CertConfig)
+		case models.SecretTypeJWKS:
+			validateJWKSConfig(c, req.JWKSConfig)
+		case models.SecretTypeKeyRing:
+			validateKeyRingConfig(c, req.KeyRingConfig)
+		case models.SecretTypeIAMAccessKey:
+			validateAccessKeyConfig(c, req.AccessKeyConfig)
 		}
 	}
 
+	if len(c.errs) > 0 {
+		return c.errs
+	}
 	return nil
 }
 
-func validateSecretARN(arn string) error {
+func validateSecretARN(c *collector, field, arn string) {
 	if arn == "" {
-		return errors.New("secret_arn cannot be empty")
+		c.add(field, CodeRequired, "cannot be empty")
+		return
 	}
 
-	// Basic length check for ARN format
-	if len(arn) < 20 {
-		return errors.New("secret_arn is too short to be valid")
+	// Basic length check for ARN format, a plain secret name may be short
+	if strings.HasPrefix(arn, "arn:") && len(arn) < 20 {
+		c.add(field, CodeInvalid, "is too short to be valid")
 	}
+}
 
-	return nil
+func validateSecretType(c *collector, secretType models.SecretType) bool {
+	switch secretType {
+	case models.SecretTypePlaintext, models.SecretTypeKeyValue, models.SecretTypeJSON, models.SecretTypeKeyPair,
+		models.SecretTypeCertificate, models.SecretTypeJWKS, models.SecretTypeKeyRing, models.SecretTypeURI,
+		models.SecretTypeDotenv, models.SecretTypeYAML, models.SecretTypeINI, models.SecretTypeProperties,
+		models.SecretTypeIAMAccessKey:
+		return true
+	case "":
+		c.add("secret_type", CodeRequired, "cannot be empty")
+	default:
+		c.add("secret_type", CodeInvalid, "unknown secret type %q", secretType)
+	}
+	return false
+}
+
+func validateAction(c *collector, req models.RotationRequest) {
+	switch req.Action {
+	case "", models.ActionRotate:
+		if req.CloneConfig != nil {
+			c.add("clone_config", CodeUnsupported, "is only used by the clone action")
+		}
+		return
+	case models.ActionClone:
+	default:
+		c.add("action", CodeInvalid, "unknown action %q", req.Action)
+		return
+	}
+
+	cfg := req.CloneConfig
+	if cfg == nil {
+		c.add("clone_config", CodeRequired, "is required for the clone action")
+		return
+	}
+	validateSecretARN(c, "clone_config.source_secret_arn", cfg.SourceSecretARN)
+	if req.CreateIfMissing != nil {
+		c.add("create_if_missing", CodeConflict, "cannot be combined with the clone action")
+	}
+	switch req.SecretType {
+	case models.SecretTypeKeyValue, models.SecretTypeJSON,
+		models.SecretTypeDotenv, models.SecretTypeYAML, models.SecretTypeINI, models.SecretTypeProperties:
+	default:
+		c.add("secret_type", CodeUnsupported, "clone is only supported for key-value, json and file secret types")
+	}
+	for old := range cfg.Substitutions {
+		if old == "" {
+			c.add("clone_config.substitutions", CodeInvalid, "cannot contain empty keys")
+		}
+	}
 }
 
-func validateSecretType(secretType models.SecretType) error {
+func validateSchemaConfig(c *collector, req models.RotationRequest) {
+	cfg := req.Schema
+	sources := 0
+	for _, set := range []bool{len(cfg.Inline) > 0, cfg.SecretARN != "", cfg.FromTag} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		c.add("schema", CodeConflict, "requires exactly one of inline, secret_arn or from_tag")
+	}
+	if cfg.FromTag && req.CreateIfMissing != nil {
+		c.add("schema.from_tag", CodeConflict, "cannot be combined with create_if_missing")
+	}
+}
+
+// usernamePrefix keeps generated names within the 32 character limit of MySQL user names.
+var usernamePrefix = regexp.MustCompile(`^[a-z][a-z0-9_]{0,11}$`)
+
+func validateConnectorConfig(c *collector, cfg *models.ConnectorConfig) {
+	switch cfg.Strategy {
+	case "", models.StrategySingleUser, models.StrategyAlternatingUsers:
+	case models.StrategyEphemeralUsers:
+		if cfg.TemplateRole == "" {
+			c.add("connector.template_role", CodeRequired, "is required for the %s strategy", cfg.Strategy)
+		}
+		if cfg.UsernamePrefix == "" {
+			c.add("connector.username_prefix", CodeRequired, "is required for the %s strategy", cfg.Strategy)
+		} else if !usernamePrefix.MatchString(cfg.UsernamePrefix) {
+			c.add("connector.username_prefix", CodeInvalid,
+				"must start with a lowercase letter and contain at most 12 lowercase letters, digits or underscores")
+		}
+		return
+	default:
+		c.add("connector.strategy", CodeInvalid, "unknown strategy %q", cfg.Strategy)
+	}
+	if cfg.TemplateRole != "" || cfg.UsernamePrefix != "" {
+		c.add("connector", CodeUnsupported, "template_role and username_prefix are only used by the %s strategy",
+			models.StrategyEphemeralUsers)
+	}
+}
+
+func validateCreateConfig(c *collector, secretType models.SecretType, cfg *models.CreateConfig) {
 	switch secretType {
-	case models.SecretTypePlaintext, models.SecretTypeKeyValue, models.SecretTypeJSON:
-		return nil
+	case models.SecretTypeURI, models.SecretTypeIAMAccessKey:
+		c.add("create_if_missing", CodeUnsupported, "is not supported for %s secrets", secretType)
+	case models.SecretTypePlaintext, models.SecretTypeJWKS, models.SecretTypeKeyRing:
+		if len(cfg.Template) > 0 {
+			c.add("create_if_missing.template", CodeUnsupported, "is only supported for secrets with named keys")
+		}
+	}
+
+	// A connector changes the password of an existing user, which a new secret does not have yet
+	if _, ok := cfg.Template[connector.FieldEngine]; ok {
+		c.add("create_if_missing.template."+connector.FieldEngine, CodeConflict, "secrets rotated through a connector must already exist")
+	}
+	for key := range cfg.Tags {
+		if key == "" {
+			c.add("create_if_missing.tags", CodeInvalid, "cannot contain empty keys")
+		}
+	}
+	for i, region := range cfg.ReplicaRegions {
+		if region == "" {
+			c.add(fmt.Sprintf("create_if_missing.replica_regions[%d]", i), CodeRequired, "cannot be empty")
+		}
+	}
+}
+
+func validatePolicy(c *collector, policy *models.RotationPolicy) {
+	if d, err := time.ParseDuration(policy.Interval); err != nil || d <= 0 {
+		c.add("policy.interval", CodeInvalid, "must be a positive duration")
+	}
+}
+
+func validateGeneratorOptions(c *collector, opts models.GeneratorOptions) {
+	switch opts.Format {
+	case "", models.GeneratorFormatPassword:
+		// Length and counts are checked as the generator sees them, after the profile raised them
+		effective, err := generator.ApplyProfile(opts)
+		if err != nil {
+			c.add("generator_options.profile", CodeInvalid, "unknown profile %q", opts.Profile)
+		}
+		validatePasswordOptions(c, effective)
+	case models.GeneratorFormatAPIToken:
+		if opts.Profile != "" {
+			c.add("generator_options.profile", CodeConflict, "is only used with the password format")
+		}
+		validateTokenOptions(c, opts.Token)
 	default:
-		return errors.New("invalid secret_type")
+		c.add("generator_options.format", CodeInvalid, "unknown format %q", opts.Format)
 	}
 }
 
-func validateKeyValueConfig(cfg *models.KeyValueConfig) error {
+// validatePasswordOptions mirrors the rules of generator.SecretGenerator. A zero length is left to
+// the generator so requests that never generate a password do not have to set it.
+func validatePasswordOptions(c *collector, opts models.GeneratorOptions) {
+	if opts.Token != nil {
+		c.add("generator_options.token", CodeConflict, "is only used with the api-token format")
+	}
+
+	if opts.Length < 0 || opts.Length > 0 && opts.Length < generator.MinSecretLength {
+		c.add("generator_options.length", CodeOutOfRange, "must be at least %d", generator.MinSecretLength)
+	}
+	if opts.MinNumberDigits < 0 {
+		c.add("generator_options.min_number_digits", CodeOutOfRange, "cannot be negative")
+	}
+	if opts.MinNumberSpecial < 0 {
+		c.add("generator_options.min_number_special", CodeOutOfRange, "cannot be negative")
+	}
+	if opts.MinNumberDigits > 0 && !opts.IncludeDigits {
+		c.add("generator_options.min_number_digits", CodeConflict, "requires include_digits")
+	}
+	if opts.MinNumberSpecial > 0 && !opts.IncludeSpecialChars {
+		c.add("generator_options.min_number_special", CodeConflict, "requires include_special_chars")
+	}
+
+	digits, special := 0, 0
+	if opts.IncludeDigits {
+		digits = max(opts.MinNumberDigits, generator.MinNumberDigits)
+	}
+	if opts.IncludeSpecialChars {
+		special = max(opts.MinNumberSpecial, generator.MinNumberSpecial)
+	}
+	if opts.Length > 0 && digits+special > opts.Length {
+		c.add("generator_options.min_number_digits", CodeConflict,
+			"%d digits and %d special characters do not fit in length %d", digits, special, opts.Length)
+	}
+}
+
+// generatedLength returns the length of the values the generator produces, or 0 when the
+// options are invalid and already reported. Generated characters are ASCII, one byte each.
+func generatedLength(opts models.GeneratorOptions) int {
+	switch opts.Format {
+	case "", models.GeneratorFormatPassword:
+		effective, err := generator.ApplyProfile(opts)
+		if err != nil {
+			return 0
+		}
+		return effective.Length
+	case models.GeneratorFormatAPIToken:
+		token := models.TokenOptions{}
+		if opts.Token != nil {
+			token = *opts.Token
+		}
+		return apitoken.Length(apitoken.Options{Prefix: token.Prefix, BodyLength: token.BodyLength})
+	default:
+		return 0
+	}
+}
+
+func validateTokenOptions(c *collector, token *models.TokenOptions) {
+	if token == nil {
+		return
+	}
+	if token.BodyLength < 0 || token.BodyLength > 0 && token.BodyLength < apitoken.MinBodyLength {
+		c.add("generator_options.token.body_length", CodeOutOfRange, "must be at least %d", apitoken.MinBodyLength)
+	}
+	if token.Checksum != "" && !apitoken.IsSupportedChecksum(token.Checksum) {
+		c.add("generator_options.token.checksum", CodeInvalid, "unknown checksum %q", token.Checksum)
+	}
+}
+
+func validateKeyValueConfig(c *collector, cfg *models.KeyValueConfig, opts models.GeneratorOptions) {
 	if cfg == nil {
-		return nil
+		return
 	}
 	// Empty KeysToRotate means rotate all keys, which is valid
-	return nil
+	rotated := make(map[string]bool, len(cfg.KeysToRotate))
+	for _, k := range cfg.KeysToRotate {
+		rotated[k] = true
+	}
+
+	for i, k := range cfg.URIKeys {
+		if k == "" {
+			c.add(fmt.Sprintf("key_value_config.uri_keys[%d]", i), CodeRequired, "cannot be empty")
+		}
+	}
+	for i, f := range cfg.DerivedFields {
+		field := fmt.Sprintf("key_value_config.derived_fields[%d]", i)
+		if f.Key == "" {
+			c.add(field+".key", CodeRequired, "cannot be empty")
+		}
+		if f.Source == "" {
+			c.add(field+".source", CodeRequired, "cannot be empty")
+		}
+		if f.Key != "" && f.Key == f.Source {
+			c.add(field+".source", CodeConflict, "key cannot be its own source")
+		}
+		if !hasher.IsSupported(f.Algorithm) {
+			c.add(field+".algorithm", CodeInvalid, "unknown algorithm %q", f.Algorithm)
+		} else if limit, length := hasher.MaxPasswordLength(f.Algorithm), generatedLength(opts); limit > 0 && length > limit {
+			c.add(field+".algorithm", CodeConflict, "%s accepts at most %d bytes, but generated values are %d long",
+				f.Algorithm, limit, length)
+		}
+		if rotated[f.Key] {
+			c.add(field+".key", CodeConflict, "derived field %s cannot also be rotated", f.Key)
+		}
+	}
+	if cfg.Metadata != nil {
+		for _, k := range []string{"rotated_at", "credential_version", "rotated_by", "expires_at"} {
+			if rotated[k] {
+				c.add("key_value_config.keys_to_rotate", CodeConflict, "metadata field %s cannot also be rotated", k)
+			}
+		}
+	}
+	templateKeys := make([]string, 0, len(cfg.Templates))
+	for key := range cfg.Templates {
+		templateKeys = append(templateKeys, key)
+	}
+	sort.Strings(templateKeys)
+	for _, key := range templateKeys {
+		text := cfg.Templates[key]
+		field := "key_value_config.templates." + key
+		if _, err := templating.Parse(key, text); err != nil {
+			c.add(field, CodeInvalid, "%v", err)
+		}
+		if rotated[key] {
+			c.add(field, CodeConflict, "template target cannot also be rotated")
+		}
+	}
+}
+
+func validateKeyPairConfig(c *collector, cfg *models.KeyPairConfig) {
+	if cfg == nil {
+		c.add("key_pair_config", CodeRequired, "is required for keypair secrets")
+		return
+	}
+
+	if !isValidKeyAlgorithm(cfg.Algorithm) {
+		c.add("key_pair_config.algorithm", CodeInvalid, "unknown algorithm %q", cfg.Algorithm)
+	}
+
+	privateField, publicField := cfg.PrivateKeyField, cfg.PublicKeyField
+	if privateField == "" {
+		privateField = "private_key"
+	}
+	if publicField == "" {
+		publicField = "public_key"
+	}
+	if privateField == publicField || cfg.SSHPublicKeyField == privateField || cfg.SSHPublicKeyField == publicField {
+		c.add("key_pair_config", CodeConflict, "fields must be distinct")
+	}
+}
+
+func validateCertificateConfig(c *collector, cfg *models.CertificateConfig) {
+	if cfg == nil {
+		c.add("certificate_config", CodeRequired, "is required for certificate secrets")
+		return
+	}
+	validateSecretARN(c, "certificate_config.ca_secret_arn", cfg.CASecretARN)
+	if !isValidKeyAlgorithm(cfg.KeyAlgorithm) {
+		c.add("certificate_config.key_algorithm", CodeInvalid, "unknown algorithm %q", cfg.KeyAlgorithm)
+	}
+	if cfg.Subject.CommonName == "" && len(cfg.DNSNames) == 0 && len(cfg.IPAddresses) == 0 && len(cfg.URIs) == 0 {
+		c.add("certificate_config.subject.common_name", CodeRequired, "a common name or at least one SAN is required")
+	}
+	for i, ip := range cfg.IPAddresses {
+		if net.ParseIP(ip) == nil {
+			c.add(fmt.Sprintf("certificate_config.ip_addresses[%d]", i), CodeInvalid, "invalid IP address %q", ip)
+		}
+	}
+	validity, err := time.ParseDuration(cfg.Validity)
+	if err != nil || validity <= 0 {
+		c.add("certificate_config.validity", CodeInvalid, "must be a positive duration")
+	}
+	if cfg.MinValidity != "" {
+		if d, err := time.ParseDuration(cfg.MinValidity); err != nil || d <= 0 {
+			c.add("certificate_config.min_validity", CodeInvalid, "must be a positive duration")
+		} else if validity > 0 && d > validity {
+			c.add("certificate_config.min_validity", CodeConflict, "cannot exceed validity")
+		}
+	}
+	if cfg.RenewBefore != "" {
+		if d, err := time.ParseDuration(cfg.RenewBefore); err != nil || d < 0 {
+			c.add("certificate_config.renew_before", CodeInvalid, "must be a non-negative duration")
+		}
+	}
+	for i, u := range cfg.ExtKeyUsages {
+		if u != "server_auth" && u != "client_auth" {
+			c.add(fmt.Sprintf("certificate_config.ext_key_usages[%d]", i), CodeInvalid, "unknown key usage %q", u)
+		}
+	}
+}
+
+func validateJWKSConfig(c *collector, cfg *models.JWKSConfig) {
+	if cfg == nil {
+		c.add("jwks_config", CodeRequired, "is required for jwks secrets")
+		return
+	}
+	if !jwks.IsSupportedAlgorithm(cfg.Algorithm) {
+		c.add("jwks_config.algorithm", CodeInvalid, "unknown algorithm %q", cfg.Algorithm)
+	}
+	if cfg.PublicSecretARN != "" {
+		validateSecretARN(c, "jwks_config.public_secret_arn", cfg.PublicSecretARN)
+	}
+	if cfg.ActivationDelay != "" {
+		if d, err := time.ParseDuration(cfg.ActivationDelay); err != nil || d < 0 {
+			c.add("jwks_config.activation_delay", CodeInvalid, "must be a non-negative duration")
+		}
+	}
+	// The ring needs room for the active key and the one waiting to take over
+	if cfg.MaxKeys != 0 && cfg.MaxKeys < 2 {
+		c.add("jwks_config.max_keys", CodeOutOfRange, "must be at least 2")
+	}
+}
+
+func validateKeyRingConfig(c *collector, cfg *models.KeyRingConfig) {
+	if cfg == nil {
+		c.add("key_ring_config", CodeRequired, "is required for keyring secrets")
+		return
+	}
+	if !keyring.IsSupportedAlgorithm(cfg.Algorithm) {
+		c.add("key_ring_config.algorithm", CodeInvalid, "unknown algorithm %q", cfg.Algorithm)
+	}
+	if cfg.Retain != nil && *cfg.Retain < 0 {
+		c.add("key_ring_config.retain", CodeOutOfRange, "cannot be negative")
+	}
+}
+
+// awsRegion matches region names such as us-east-1 or us-gov-west-1.
+var awsRegion = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+func validateAccessKeyConfig(c *collector, cfg *models.AccessKeyConfig) {
+	if cfg == nil {
+		c.add("access_key_config", CodeRequired, "is required for iam-access-key secrets")
+		return
+	}
+	if cfg.UserName == "" {
+		c.add("access_key_config.user_name", CodeRequired, "cannot be empty")
+	}
+	if cfg.SMTPRegion != "" && !awsRegion.MatchString(cfg.SMTPRegion) {
+		c.add("access_key_config.smtp_region", CodeInvalid, "%q is not an AWS region", cfg.SMTPRegion)
+	}
+}
+
+func isValidKeyAlgorithm(alg models.KeyAlgorithm) bool {
+	switch alg {
+	case models.KeyAlgorithmRSA2048, models.KeyAlgorithmRSA3072, models.KeyAlgorithmRSA4096,
+		models.KeyAlgorithmECDSAP256, models.KeyAlgorithmECDSAP384, models.KeyAlgorithmEd25519:
+		return true
+	default:
+		return false
+	}
 }
@@ -0,0 +1,292 @@
+package validator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/darthlynx/secret-rotation-lambda/internal/models"
+)
+
+const testARN = "arn:aws:secretsmanager:us-east-1:123456789012:secret:test"
+
+type fieldCode struct {
+	field, code string
+}
+
+func TestValidateRotationRequest(t *testing.T) {
+	negative := -1
+	tests := []struct {
+		name string
+		req  models.RotationRequest
+		want []fieldCode
+	}{
+		{
+			name: "valid password request",
+			req: models.RotationRequest{
+				SecretARN:  testARN,
+				SecretType: models.SecretTypePlaintext,
+				GeneratorOpts: models.GeneratorOptions{
+					Length: 16, IncludeDigits: true, IncludeSpecialChars: true, MinNumberDigits: 4, MinNumberSpecial: 4,
+				},
+			},
+		},
+		{
+			name: "all top-level problems reported together",
+			req:  models.RotationRequest{GeneratorOpts: models.GeneratorOptions{Format: "uuid"}},
+			want: []fieldCode{
+				{"secret_arn", CodeRequired},
+				{"secret_type", CodeRequired},
+				{"generator_options.format", CodeInvalid},
+			},
+		},
+		{
+			name: "digits and special characters exceed length",
+			req: models.RotationRequest{
+				SecretARN:  testARN,
+				SecretType: models.SecretTypePlaintext,
+				GeneratorOpts: models.GeneratorOptions{
+					Length: 8, IncludeDigits: true, IncludeSpecialChars: true, MinNumberDigits: 5, MinNumberSpecial: 4,
+				},
+			},
+			want: []fieldCode{{"generator_options.min_number_digits", CodeConflict}},
+		},
+		{
+			name: "negative and contradictory generator options",
+			req: models.RotationRequest{
+				SecretARN:  testARN,
+				SecretType: models.SecretTypePlaintext,
+				GeneratorOpts: models.GeneratorOptions{
+					Length: -1, MinNumberDigits: 2, MinNumberSpecial: -3,
+					Token: &models.TokenOptions{Prefix: "x_"},
+				},
+			},
+			want: []fieldCode{
+				{"generator_options.token", CodeConflict},
+				{"generator_options.length", CodeOutOfRange},
+				{"generator_options.min_number_special", CodeOutOfRange},
+				{"generator_options.min_number_digits", CodeConflict},
+			},
+		},
+		{
+			name: "plain secret name",
+			req:  models.RotationRequest{SecretARN: "prod/db", SecretType: models.SecretTypePlaintext},
+		},
+		{
+			name: "short ARN",
+			req:  models.RotationRequest{SecretARN: "arn:aws:sm:x", SecretType: models.SecretTypePlaintext},
+			want: []fieldCode{{"secret_arn", CodeInvalid}},
+		},
+		{
+			name: "negative key ring retain",
+			req: models.RotationRequest{
+				SecretARN:     testARN,
+				SecretType:    models.SecretTypeKeyRing,
+				KeyRingConfig: &models.KeyRingConfig{Algorithm: "aes-256-gcm", Retain: &negative},
+			},
+			want: []fieldCode{{"key_ring_config.retain", CodeOutOfRange}},
+		},
+		{
+			name: "short length",
+			req: models.RotationRequest{
+				SecretARN:     testARN,
+				SecretType:    models.SecretTypePlaintext,
+				GeneratorOpts: models.GeneratorOptions{Length: 4},
+			},
+			want: []fieldCode{{"generator_options.length", CodeOutOfRange}},
+		},
+		{
+			name: "active directory profile raises length",
+			req: models.RotationRequest{
+				SecretARN:  testARN,
+				SecretType: models.SecretTypePlaintext,
+				GeneratorOpts: models.GeneratorOptions{
+					Profile: models.GeneratorProfileActiveDirectory, Length: 8, MinNumberDigits: 6, MinNumberSpecial: 6,
+				},
+			},
+		},
+		{
+			name: "unknown profile",
+			req: models.RotationRequest{
+				SecretARN:     testARN,
+				SecretType:    models.SecretTypePlaintext,
+				GeneratorOpts: models.GeneratorOptions{Profile: "windows-2003"},
+			},
+			want: []fieldCode{{"generator_options.profile", CodeInvalid}},
+		},
+		{
+			name: "api token options",
+			req: models.RotationRequest{
+				SecretARN:  testARN,
+				SecretType: models.SecretTypePlaintext,
+				GeneratorOpts: models.GeneratorOptions{
+					Format: models.GeneratorFormatAPIToken,
+					Token:  &models.TokenOptions{BodyLength: 5, Checksum: "md5"},
+				},
+			},
+			want: []fieldCode{
+				{"generator_options.token.body_length", CodeOutOfRange},
+				{"generator_options.token.checksum", CodeInvalid},
+			},
+		},
+		{
+			name: "nested config errors carry indexes",
+			req: models.RotationRequest{
+				SecretARN:  testARN,
+				SecretType: models.SecretTypeKeyValue,
+				KeyValueConfig: &models.KeyValueConfig{
+					KeysToRotate: []string{"password", "password_hash"},
+					DerivedFields: []models.DerivedField{
+						{Key: "password_hash", Source: "password", Algorithm: "bcrypt"},
+						{Key: "", Source: "password", Algorithm: "sha1"},
+					},
+				},
+			},
+			want: []fieldCode{
+				{"key_value_config.derived_fields[0].key", CodeConflict},
+				{"key_value_config.derived_fields[1].key", CodeRequired},
+				{"key_value_config.derived_fields[1].algorithm", CodeInvalid},
+			},
+		},
+		{
+			name: "bcrypt derived field longer than 72 bytes",
+			req: models.RotationRequest{
+				SecretARN:     testARN,
+				SecretType:    models.SecretTypeKeyValue,
+				GeneratorOpts: models.GeneratorOptions{Length: 96},
+				KeyValueConfig: &models.KeyValueConfig{
+					KeysToRotate: []string{"password"},
+					DerivedFields: []models.DerivedField{
+						{Key: "password_bcrypt", Source: "password", Algorithm: "bcrypt"},
+						{Key: "password_argon2id", Source: "password", Algorithm: "argon2id"},
+						{Key: "htpasswd", Source: "password", Algorithm: "htpasswd"},
+					},
+				},
+			},
+			want: []fieldCode{
+				{"key_value_config.derived_fields[0].algorithm", CodeConflict},
+				{"key_value_config.derived_fields[2].algorithm", CodeConflict},
+			},
+		},
+		{
+			name: "missing type config",
+			req: models.RotationRequest{
+				SecretARN:  testARN,
+				SecretType: models.SecretTypeKeyRing,
+			},
+			want: []fieldCode{{"key_ring_config", CodeRequired}},
+		},
+		{
+			name: "access key config",
+			req: models.RotationRequest{
+				SecretARN:       testARN,
+				SecretType:      models.SecretTypeIAMAccessKey,
+				AccessKeyConfig: &models.AccessKeyConfig{SMTPRegion: "Ireland"},
+				CreateIfMissing: &models.CreateConfig{},
+			},
+			want: []fieldCode{
+				{"create_if_missing", CodeUnsupported},
+				{"access_key_config.user_name", CodeRequired},
+				{"access_key_config.smtp_region", CodeInvalid},
+			},
+		},
+		{
+			name: "create connector secret",
+			req: models.RotationRequest{
+				SecretARN:  testARN,
+				SecretType: models.SecretTypeKeyValue,
+				Connector:  &models.ConnectorConfig{},
+				CreateIfMissing: &models.CreateConfig{
+					Template: map[string]interface{}{"engine": "postgres", "username": "app"},
+				},
+			},
+			want: []fieldCode{
+				{"create_if_missing.template.engine", CodeConflict},
+				{"create_if_missing", CodeConflict},
+			},
+		},
+		{
+			name: "certificate minimum validity exceeds validity",
+			req: models.RotationRequest{
+				SecretARN:  testARN,
+				SecretType: models.SecretTypeCertificate,
+				CertConfig: &models.CertificateConfig{
+					CASecretARN:  testARN,
+					KeyAlgorithm: models.KeyAlgorithmECDSAP256,
+					Subject:      models.CertificateSubject{CommonName: "billing.mesh.internal"},
+					Validity:     "720h",
+					MinValidity:  "1000h",
+				},
+			},
+			want: []fieldCode{{"certificate_config.min_validity", CodeConflict}},
+		},
+		{
+			name: "clone without config",
+			req: models.RotationRequest{
+				Action:     models.ActionClone,
+				SecretARN:  "staging/db",
+				SecretType: models.SecretTypeKeyValue,
+			},
+			want: []fieldCode{{"clone_config", CodeRequired}},
+		},
+		{
+			name: "ephemeral users settings",
+			req: models.RotationRequest{
+				SecretARN:  testARN,
+				SecretType: models.SecretTypeKeyValue,
+				Connector:  &models.ConnectorConfig{Strategy: models.StrategyEphemeralUsers, UsernamePrefix: "App-Users"},
+			},
+			want: []fieldCode{
+				{"connector.template_role", CodeRequired},
+				{"connector.username_prefix", CodeInvalid},
+			},
+		},
+		{
+			name: "ephemeral settings with another strategy",
+			req: models.RotationRequest{
+				SecretARN:  testARN,
+				SecretType: models.SecretTypeKeyValue,
+				Connector:  &models.ConnectorConfig{Strategy: models.StrategyAlternatingUsers, TemplateRole: "app_reader"},
+			},
+			want: []fieldCode{{"connector", CodeUnsupported}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRotationRequest(tt.req)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateRotationRequest() error: %v", err)
+				}
+				return
+			}
+
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("ValidateRotationRequest() error = %v, want ValidationErrors", err)
+			}
+			var got []fieldCode
+			for _, e := range errs {
+				if e.Message == "" {
+					t.Errorf("%s has no message", e.Field)
+				}
+				got = append(got, fieldCode{e.Field, e.Code})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrors_Error(t *testing.T) {
+	errs := ValidationErrors{
+		{Field: "secret_arn", Code: CodeRequired, Message: "cannot be empty"},
+		{Field: "generator_options.length", Code: CodeOutOfRange, Message: "must be at least 8"},
+	}
+	want := "invalid request: secret_arn: cannot be empty; generator_options.length: must be at least 8"
+	if got := errs.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
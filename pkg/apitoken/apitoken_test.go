@@ -0,0 +1,100 @@
+package apitoken
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAndVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantLen int
+	}{
+		{
+			name:    "defaults",
+			opts:    Options{Prefix: "acme_live_"},
+			wantLen: len("acme_live_") + DefaultBodyLength + checksumLength,
+		},
+		{
+			name:    "crc32c with custom length",
+			opts:    Options{Prefix: "acme_test_", BodyLength: 40, Checksum: ChecksumCRC32C},
+			wantLen: len("acme_test_") + 40 + checksumLength,
+		},
+		{
+			name:    "no prefix",
+			opts:    Options{BodyLength: 24},
+			wantLen: 24 + checksumLength,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := Generate(tt.opts)
+			if err != nil {
+				t.Fatalf("Generate() error: %v", err)
+			}
+			if !strings.HasPrefix(token, tt.opts.Prefix) {
+				t.Errorf("Generate() = %s, want prefix %s", token, tt.opts.Prefix)
+			}
+			if len(token) != tt.wantLen {
+				t.Errorf("Generate() length = %d, want %d", len(token), tt.wantLen)
+			}
+			if got := Length(tt.opts); got != tt.wantLen {
+				t.Errorf("Length() = %d, want %d", got, tt.wantLen)
+			}
+			if err := Verify(token, tt.opts); err != nil {
+				t.Errorf("Verify() error: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerify_Rejects(t *testing.T) {
+	opts := Options{Prefix: "acme_live_"}
+	token, err := Generate(opts)
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	// Flip one body character to another valid base62 character
+	body := []byte(token)
+	i := len(opts.Prefix) + 3
+	if body[i] == 'a' {
+		body[i] = 'b'
+	} else {
+		body[i] = 'a'
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		opts    Options
+		wantErr error
+	}{
+		{name: "wrong prefix", token: "acme_test_" + token[len(opts.Prefix):], opts: opts, wantErr: ErrInvalidFormat},
+		{name: "truncated", token: token[:len(token)-1], opts: opts, wantErr: ErrInvalidFormat},
+		{name: "invalid character", token: token[:len(token)-1] + "-", opts: opts, wantErr: ErrInvalidFormat},
+		{name: "tampered body", token: string(body), opts: opts, wantErr: ErrChecksumMismatch},
+		{name: "other checksum algorithm", token: token, opts: Options{Prefix: "acme_live_", Checksum: ChecksumCRC32C}, wantErr: ErrChecksumMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.token, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerate_InvalidOptions(t *testing.T) {
+	if _, err := Generate(Options{BodyLength: MinBodyLength - 1}); err == nil {
+		t.Errorf("Generate() expected error for short body, got nil")
+	}
+	if _, err := Generate(Options{Checksum: "md5"}); err == nil {
+		t.Errorf("Generate() expected error for unknown checksum, got nil")
+	}
+}
@@ -0,0 +1,314 @@
+// Package jwks manages a JWT signing key ring stored as a JWK Set.
+//
+// Every key carries created_at and active_at timestamps next to its JWK parameters. Signers
+// use SigningKey to pick the private half of the active key; verifiers get the ring without
+// private parameters from PublicJWKS, which can be published on its own.
+package jwks
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Supported JWS signing algorithms.
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgES384 = "ES384"
+	AlgEdDSA = "EdDSA"
+)
+
+// keyGenerators create the private key used with each JWS algorithm.
+var keyGenerators = map[string]func() (crypto.Signer, error){
+	AlgRS256: func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 2048) },
+	AlgES256: func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P256(), rand.Reader) },
+	AlgES384: func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P384(), rand.Reader) },
+	AlgEdDSA: func() (crypto.Signer, error) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	},
+}
+
+// Key is a JSON Web Key with rotation bookkeeping. Timestamps are seconds since the epoch.
+type Key struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use,omitempty"`
+
+	// RSA public and private parameters
+	N  string `json:"n,omitempty"`
+	E  string `json:"e,omitempty"`
+	D  string `json:"d,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	DP string `json:"dp,omitempty"`
+	DQ string `json:"dq,omitempty"`
+	QI string `json:"qi,omitempty"`
+
+	// EC and OKP parameters, D above holds the private part
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+
+	CreatedAt int64 `json:"created_at,omitempty"`
+	ActiveAt  int64 `json:"active_at,omitempty"`
+}
+
+// KeySet is a JWK Set used as a signing key ring.
+type KeySet struct {
+	Keys []Key `json:"keys"`
+}
+
+// IsSupportedAlgorithm reports whether keys can be generated for the JWS algorithm.
+func IsSupportedAlgorithm(alg string) bool {
+	_, ok := keyGenerators[alg]
+	return ok
+}
+
+// Parse decodes a key ring stored as a JWK Set. An empty document is an empty ring.
+func Parse(data string) (*KeySet, error) {
+	set := &KeySet{}
+	if data == "" {
+		return set, nil
+	}
+	if err := json.Unmarshal([]byte(data), set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// NewKey generates a signing key that becomes active at activeAt.
+func NewKey(alg string, now, activeAt time.Time) (Key, error) {
+	generate, ok := keyGenerators[alg]
+	if !ok {
+		return Key{}, fmt.Errorf("unsupported JWS algorithm: %s", alg)
+	}
+	signer, err := generate()
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to generate %s key: %w", alg, err)
+	}
+
+	key, err := fromSigner(signer)
+	if err != nil {
+		return Key{}, err
+	}
+	key.Algorithm = alg
+	key.Use = "sig"
+	key.KeyID = key.thumbprint()
+	key.CreatedAt = now.Unix()
+	key.ActiveAt = activeAt.Unix()
+	return key, nil
+}
+
+// Rotate adds a new key that activates after delay and drops keys beyond maxKeys generations.
+// The key that is active at now is always retained so signing never stops during the handover.
+// The very first key of an empty ring is activated immediately.
+func (s *KeySet) Rotate(alg string, now time.Time, delay time.Duration, maxKeys int) (Key, error) {
+	activeAt := now.Add(delay)
+	if len(s.Keys) == 0 {
+		activeAt = now
+	}
+	key, err := NewKey(alg, now, activeAt)
+	if err != nil {
+		return Key{}, err
+	}
+
+	active, hasActive := s.Active(now)
+	// Prepend so the new key wins ties with keys created within the same second
+	s.Keys = append([]Key{key}, s.Keys...)
+	sort.SliceStable(s.Keys, func(i, j int) bool { return s.Keys[i].CreatedAt > s.Keys[j].CreatedAt })
+
+	kept := make([]Key, 0, len(s.Keys))
+	for i, k := range s.Keys {
+		if i < maxKeys || (hasActive && k.KeyID == active.KeyID) {
+			kept = append(kept, k)
+		}
+	}
+	s.Keys = kept
+
+	return key, nil
+}
+
+// Active returns the most recently activated key at now.
+func (s *KeySet) Active(now time.Time) (Key, bool) {
+	var active Key
+	found := false
+	for _, k := range s.Keys {
+		if k.ActiveAt <= now.Unix() && (!found || k.ActiveAt > active.ActiveAt ||
+			(k.ActiveAt == active.ActiveAt && k.CreatedAt > active.CreatedAt)) {
+			active = k
+			found = true
+		}
+	}
+	return active, found
+}
+
+// SigningKey returns the key ID and private key of the active key.
+func (s *KeySet) SigningKey(now time.Time) (string, crypto.Signer, error) {
+	key, ok := s.Active(now)
+	if !ok {
+		return "", nil, errors.New("no active signing key")
+	}
+	signer, err := key.Signer()
+	if err != nil {
+		return "", nil, err
+	}
+	return key.KeyID, signer, nil
+}
+
+// Public returns the key set without private parameters and bookkeeping, ready to be published.
+func (s *KeySet) Public() *KeySet {
+	public := &KeySet{Keys: make([]Key, 0, len(s.Keys))}
+	for _, k := range s.Keys {
+		public.Keys = append(public.Keys, k.public())
+	}
+	return public
+}
+
+// PublicJWKS converts a stored key ring into its public JWK Set document.
+func PublicJWKS(data string) (string, error) {
+	set, err := Parse(data)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(set.Public())
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (k Key) public() Key {
+	return Key{
+		KeyType:   k.KeyType,
+		KeyID:     k.KeyID,
+		Algorithm: k.Algorithm,
+		Use:       k.Use,
+		N:         k.N,
+		E:         k.E,
+		Curve:     k.Curve,
+		X:         k.X,
+		Y:         k.Y,
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint used as the key ID.
+func (k Key) thumbprint() string {
+	var members string
+	switch k.KeyType {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k.E, k.N)
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, k.Curve, k.X, k.Y)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, k.Curve, k.X)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return encode(sum[:])
+}
+
+// Signer reconstructs the private key from the JWK parameters.
+func (k Key) Signer() (crypto.Signer, error) {
+	if k.D == "" {
+		return nil, fmt.Errorf("key %s has no private part", k.KeyID)
+	}
+	switch k.KeyType {
+	case "RSA":
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: decodeInt(k.N), E: int(decodeInt(k.E).Int64())},
+			D:         decodeInt(k.D),
+			Primes:    []*big.Int{decodeInt(k.P), decodeInt(k.Q)},
+		}
+		if err := key.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid RSA key %s: %w", k.KeyID, err)
+		}
+		key.Precompute()
+		return key, nil
+	case "EC":
+		curve, err := curveByName(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: curve, X: decodeInt(k.X), Y: decodeInt(k.Y)},
+			D:         decodeInt(k.D),
+		}, nil
+	case "OKP":
+		seed, err := base64.RawURLEncoding.DecodeString(k.D)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid Ed25519 key %s", k.KeyID)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.KeyType)
+	}
+}
+
+func fromSigner(signer crypto.Signer) (Key, error) {
+	switch key := signer.(type) {
+	case *rsa.PrivateKey:
+		key.Precompute()
+		return Key{
+			KeyType: "RSA",
+			N:       encode(key.N.Bytes()),
+			E:       encode(big.NewInt(int64(key.E)).Bytes()),
+			D:       encode(key.D.Bytes()),
+			P:       encode(key.Primes[0].Bytes()),
+			Q:       encode(key.Primes[1].Bytes()),
+			DP:      encode(key.Precomputed.Dp.Bytes()),
+			DQ:      encode(key.Precomputed.Dq.Bytes()),
+			QI:      encode(key.Precomputed.Qinv.Bytes()),
+		}, nil
+	case *ecdsa.PrivateKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return Key{
+			KeyType: "EC",
+			Curve:   key.Curve.Params().Name,
+			X:       encode(key.X.FillBytes(make([]byte, size))),
+			Y:       encode(key.Y.FillBytes(make([]byte, size))),
+			D:       encode(key.D.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PrivateKey:
+		return Key{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       encode(key.Public().(ed25519.PublicKey)),
+			D:       encode(key.Seed()),
+		}, nil
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %T", signer)
+	}
+}
+
+func curveByName(name string) (elliptic.Curve, error) {
+	switch name {
+	case "P-256":
+		return elliptic.P256(), nil
+	case "P-384":
+		return elliptic.P384(), nil
+	default:
+		return nil, fmt.Errorf("unsupported curve: %s", name)
+	}
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeInt(s string) *big.Int {
+	b, _ := base64.RawURLEncoding.DecodeString(s)
+	return new(big.Int).SetBytes(b)
+}
@@ -0,0 +1,158 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewKey_SignerRoundTrip(t *testing.T) {
+	now := time.Now()
+	digest := sha256.Sum256([]byte("payload"))
+
+	for _, alg := range []string{AlgRS256, AlgES256, AlgES384, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			key, err := NewKey(alg, now, now)
+			if err != nil {
+				t.Fatalf("NewKey() error: %v", err)
+			}
+			if key.KeyID == "" || key.Algorithm != alg || key.Use != "sig" {
+				t.Errorf("NewKey() = kid %q alg %q use %q", key.KeyID, key.Algorithm, key.Use)
+			}
+
+			signer, err := key.Signer()
+			if err != nil {
+				t.Fatalf("Signer() error: %v", err)
+			}
+
+			switch pub := signer.Public().(type) {
+			case *rsa.PublicKey:
+				sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+				if err != nil {
+					t.Fatalf("Sign() error: %v", err)
+				}
+				if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+					t.Errorf("signature does not verify: %v", err)
+				}
+			case *ecdsa.PublicKey:
+				sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+				if err != nil {
+					t.Fatalf("Sign() error: %v", err)
+				}
+				if !ecdsa.VerifyASN1(pub, digest[:], sig) {
+					t.Errorf("signature does not verify")
+				}
+			case ed25519.PublicKey:
+				sig, err := signer.Sign(rand.Reader, []byte("payload"), crypto.Hash(0))
+				if err != nil {
+					t.Fatalf("Sign() error: %v", err)
+				}
+				if !ed25519.Verify(pub, []byte("payload"), sig) {
+					t.Errorf("signature does not verify")
+				}
+			}
+		})
+	}
+}
+
+func TestKeySet_Rotate(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	delay := 24 * time.Hour
+	set := &KeySet{}
+
+	first, err := set.Rotate(AlgES256, start, delay, 3)
+	if err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+	if active, ok := set.Active(start); !ok || active.KeyID != first.KeyID {
+		t.Errorf("first key of an empty ring should be active immediately")
+	}
+
+	second, err := set.Rotate(AlgES256, start.Add(time.Hour), delay, 3)
+	if err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+	if active, _ := set.Active(start.Add(2 * time.Hour)); active.KeyID != first.KeyID {
+		t.Errorf("new key should not be active before the delay has passed")
+	}
+	if active, _ := set.Active(start.Add(time.Hour + delay)); active.KeyID != second.KeyID {
+		t.Errorf("new key should be active once the delay has passed")
+	}
+
+	for i := 2; i <= 4; i++ {
+		if _, err := set.Rotate(AlgES256, start.Add(time.Duration(i)*48*time.Hour), delay, 3); err != nil {
+			t.Fatalf("Rotate() error: %v", err)
+		}
+	}
+	if len(set.Keys) != 3 {
+		t.Errorf("len(Keys) = %d, want 3", len(set.Keys))
+	}
+	for _, k := range set.Keys {
+		if k.KeyID == first.KeyID || k.KeyID == second.KeyID {
+			t.Errorf("key %s should have been pruned", k.KeyID)
+		}
+	}
+}
+
+func TestKeySet_RotateKeepsActiveKey(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	set := &KeySet{}
+	first, _ := set.Rotate(AlgEdDSA, start, time.Hour, 2)
+
+	// Two rotations inside the activation delay: the first key is still the only active one.
+	if _, err := set.Rotate(AlgEdDSA, start.Add(time.Minute), time.Hour, 2); err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+	if _, err := set.Rotate(AlgEdDSA, start.Add(2*time.Minute), time.Hour, 2); err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+
+	kid, _, err := set.SigningKey(start.Add(3 * time.Minute))
+	if err != nil {
+		t.Fatalf("SigningKey() error: %v", err)
+	}
+	if kid != first.KeyID {
+		t.Errorf("SigningKey() kid = %s, want %s", kid, first.KeyID)
+	}
+}
+
+func TestPublicJWKS(t *testing.T) {
+	now := time.Now()
+	set := &KeySet{}
+	if _, err := set.Rotate(AlgRS256, now, 0, 3); err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+	if _, err := set.Rotate(AlgES384, now, 0, 3); err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+	data, _ := json.Marshal(set)
+
+	public, err := PublicJWKS(string(data))
+	if err != nil {
+		t.Fatalf("PublicJWKS() error: %v", err)
+	}
+
+	for _, private := range []string{`"d"`, `"p"`, `"q"`, `"dp"`, `"dq"`, `"qi"`, `"created_at"`} {
+		if strings.Contains(public, private) {
+			t.Errorf("public JWKS contains %s: %s", private, public)
+		}
+	}
+
+	parsed, err := Parse(public)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if len(parsed.Keys) != 2 {
+		t.Errorf("len(Keys) = %d, want 2", len(parsed.Keys))
+	}
+	if _, err := parsed.Keys[0].Signer(); err == nil {
+		t.Errorf("Signer() on public key expected error, got nil")
+	}
+}